@@ -1,8 +1,14 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"flag"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -16,6 +22,39 @@ var (
 	Base string
 	File string
 	DataBase string
+	AdminToken string
+	SecretKey string
+	RedirectCode = http.StatusTemporaryRedirect
+	AllowedSchemes = []string{"http", "https"}
+	NormalizeURLs = true
+	SortQuery bool
+	BlocklistFile string
+	AllowlistFile string
+	PolicyReload = 30 * time.Second
+	RateLimitCreate string
+	RateLimitRedirect string
+	RateLimitKey = "ip"
+	RateLimitRedis string
+	MaxBodySize int64 = 1 << 20
+	MaxBatchSize = 1000
+	MaxURLLength = 2048
+	TracingEndpoint string
+	TracingInsecure bool
+	TracingRatio = 1.0
+	LogLevel = "info"
+	LogFormat = "json"
+	ShutdownDelay = 5 * time.Second
+	ShutdownTimeout = 10 * time.Second
+	JWTKeysFile string
+	JWTAudience string
+	JWTIssuer string
+	JWTLeeway = 30 * time.Second
+	TenantsFile string
+	UserDailyLimit int
+	UserActiveLimit int
+	RateLimitUnlock = "5/m"
+	UnlockTTL = 10 * time.Minute
+	ClickFlush = 10 * time.Second
 )
 
 func ParseFlag() {
@@ -24,14 +63,191 @@ func ParseFlag() {
 	Base = os.Getenv("BASE_URL")
 	File = os.Getenv("FILE_STORAGE_PATH")
 	DataBase = os.Getenv("DATABASE_DSN")
+	AdminToken = os.Getenv("ADMIN_TOKEN")
+	SecretKey = os.Getenv("SECRET_KEY")
 	
 
 	servFlag := flag.String("a", defaultServ, "address  to run server")
 	baseFlag := flag.String("b", defaultBase, "base address for short URL")
 	fileFlag := flag.String("f", defaultFile, "path to file to save short urls")
 	flag.StringVar(&DataBase, "d", "", "string for database connection")
+	adminFlag := flag.String("t", "", "bearer token for admin API, admin API is disabled if empty")
+	keyFlag := flag.String("k", "", "key for signing user cookies, random if empty")
+	flag.IntVar(&RedirectCode, "r", http.StatusTemporaryRedirect, "default redirect status code: 301, 302, 307 or 308")
+	schemesFlag := flag.String("schemes", "http,https", "comma separated list of allowed url schemes")
+	flag.BoolVar(&NormalizeURLs, "normalize", true, "normalize urls before saving")
+	flag.BoolVar(&SortQuery, "sort-query", false, "sort query parameters during normalization")
+	blocklistFlag := flag.String("blocklist", "", "path to file with blocked destinations")
+	allowlistFlag := flag.String("allowlist", "", "path to file with allowed destinations, enables corporate mode")
+	flag.DurationVar(&PolicyReload, "policy-reload", 30*time.Second, "interval for checking blocklist and allowlist changes")
+	rateCreateFlag := flag.String("rate-create", "", "rate limit for creating links, e.g. 20/m, disabled if empty")
+	rateRedirectFlag := flag.String("rate-redirect", "", "rate limit for redirects, e.g. 100/s, disabled if empty")
+	rateKeyFlag := flag.String("rate-key", "ip", "rate limit client key: ip or user")
+	rateRedisFlag := flag.String("rate-redis", "", "redis url for shared rate limit state, in-memory if empty")
+	flag.Int64Var(&MaxBodySize, "max-body", 1<<20, "maximum request body size in bytes, also after decompression")
+	flag.IntVar(&MaxBatchSize, "max-batch", 1000, "maximum number of urls in batch request")
+	flag.IntVar(&MaxURLLength, "max-url", 2048, "maximum length of original url")
+	traceEndpointFlag := flag.String("trace-endpoint", "", "OTLP/HTTP collector address for traces, e.g. localhost:4318, tracing export is disabled if empty")
+	flag.BoolVar(&TracingInsecure, "trace-insecure", false, "send traces to collector over plain HTTP")
+	flag.Float64Var(&TracingRatio, "trace-ratio", 1.0, "fraction of traces to sample, from 0 to 1")
+	logLevelFlag := flag.String("log-level", "info", "log level: debug, info, warn or error")
+	logFormatFlag := flag.String("log-format", "json", "log encoding: json or console")
+	flag.DurationVar(&ShutdownDelay, "shutdown-delay", 5*time.Second, "time between failing readiness and stopping the listener on shutdown")
+	flag.DurationVar(&ShutdownTimeout, "shutdown-timeout", 10*time.Second, "time to finish in-flight requests on shutdown")
+	jwtKeysFlag := flag.String("jwt-jwks", "", "path to JWKS file with identity provider keys, JWT authentication is disabled if empty")
+	jwtAudienceFlag := flag.String("jwt-audience", "", "required JWT audience, not checked if empty")
+	jwtIssuerFlag := flag.String("jwt-issuer", "", "required JWT issuer, not checked if empty")
+	flag.DurationVar(&JWTLeeway, "jwt-leeway", 30*time.Second, "allowed clock skew for JWT expiry checks")
+	tenantsFlag := flag.String("tenants", "", "path to JSON file with tenants and their short domains, single tenant with base address if empty")
+	flag.IntVar(&UserDailyLimit, "quota-daily", 0, "maximum number of links a user can create per day (UTC), unlimited if 0")
+	flag.IntVar(&UserActiveLimit, "quota-active", 0, "maximum number of active links per user, unlimited if 0")
+	rateUnlockFlag := flag.String("rate-unlock", "5/m", "rate limit for password attempts on protected links, disabled if empty")
+	flag.DurationVar(&UnlockTTL, "unlock-ttl", 10*time.Minute, "how long a protected link stays unlocked after entering the password")
+	flag.DurationVar(&ClickFlush, "click-flush", 10*time.Second, "interval for saving click counters to the storage file")
 	flag.Parse()
 
+	if code, err := strconv.Atoi(os.Getenv("REDIRECT_CODE")); err == nil {
+		RedirectCode = code
+	}
+
+	schemes := os.Getenv("ALLOWED_SCHEMES")
+	if schemes == "" {
+		schemes = *schemesFlag
+	}
+	AllowedSchemes = strings.Split(schemes, ",")
+
+	if v, err := strconv.ParseBool(os.Getenv("NORMALIZE_URLS")); err == nil {
+		NormalizeURLs = v
+	}
+
+	if v, err := strconv.ParseBool(os.Getenv("SORT_QUERY")); err == nil {
+		SortQuery = v
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("POLICY_RELOAD_INTERVAL")); err == nil {
+		PolicyReload = d
+	}
+
+	BlocklistFile = os.Getenv("BLOCKLIST_FILE")
+	if BlocklistFile == "" {
+		BlocklistFile = *blocklistFlag
+	}
+
+	AllowlistFile = os.Getenv("ALLOWLIST_FILE")
+	if AllowlistFile == "" {
+		AllowlistFile = *allowlistFlag
+	}
+
+	RateLimitCreate = os.Getenv("RATE_LIMIT_CREATE")
+	if RateLimitCreate == "" {
+		RateLimitCreate = *rateCreateFlag
+	}
+
+	RateLimitRedirect = os.Getenv("RATE_LIMIT_REDIRECT")
+	if RateLimitRedirect == "" {
+		RateLimitRedirect = *rateRedirectFlag
+	}
+
+	RateLimitKey = os.Getenv("RATE_LIMIT_KEY")
+	if RateLimitKey == "" {
+		RateLimitKey = *rateKeyFlag
+	}
+
+	RateLimitRedis = os.Getenv("RATE_LIMIT_REDIS")
+	if RateLimitRedis == "" {
+		RateLimitRedis = *rateRedisFlag
+	}
+
+	if n, err := strconv.ParseInt(os.Getenv("MAX_BODY_SIZE"), 10, 64); err == nil {
+		MaxBodySize = n
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("MAX_BATCH_SIZE")); err == nil {
+		MaxBatchSize = n
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("MAX_URL_LENGTH")); err == nil {
+		MaxURLLength = n
+	}
+
+	TracingEndpoint = os.Getenv("TRACING_ENDPOINT")
+	if TracingEndpoint == "" {
+		TracingEndpoint = *traceEndpointFlag
+	}
+
+	if v, err := strconv.ParseBool(os.Getenv("TRACING_INSECURE")); err == nil {
+		TracingInsecure = v
+	}
+
+	if v, err := strconv.ParseFloat(os.Getenv("TRACING_RATIO"), 64); err == nil {
+		TracingRatio = v
+	}
+
+	LogLevel = os.Getenv("LOG_LEVEL")
+	if LogLevel == "" {
+		LogLevel = *logLevelFlag
+	}
+
+	LogFormat = os.Getenv("LOG_FORMAT")
+	if LogFormat == "" {
+		LogFormat = *logFormatFlag
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("SHUTDOWN_DELAY")); err == nil {
+		ShutdownDelay = d
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT")); err == nil {
+		ShutdownTimeout = d
+	}
+
+	JWTKeysFile = os.Getenv("JWT_JWKS_FILE")
+	if JWTKeysFile == "" {
+		JWTKeysFile = *jwtKeysFlag
+	}
+
+	JWTAudience = os.Getenv("JWT_AUDIENCE")
+	if JWTAudience == "" {
+		JWTAudience = *jwtAudienceFlag
+	}
+
+	JWTIssuer = os.Getenv("JWT_ISSUER")
+	if JWTIssuer == "" {
+		JWTIssuer = *jwtIssuerFlag
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("JWT_LEEWAY")); err == nil {
+		JWTLeeway = d
+	}
+
+	TenantsFile = os.Getenv("TENANTS_FILE")
+	if TenantsFile == "" {
+		TenantsFile = *tenantsFlag
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("USER_QUOTA_DAILY")); err == nil {
+		UserDailyLimit = n
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("USER_QUOTA_ACTIVE")); err == nil {
+		UserActiveLimit = n
+	}
+
+	//Пустое значение переменной отключает ограничение, поэтому проверяем наличие, а не пустоту
+	if v, ok := os.LookupEnv("RATE_LIMIT_UNLOCK"); ok {
+		RateLimitUnlock = v
+	} else {
+		RateLimitUnlock = *rateUnlockFlag
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("UNLOCK_TTL")); err == nil {
+		UnlockTTL = d
+	}
+
+	if d, err := time.ParseDuration(os.Getenv("CLICK_FLUSH_INTERVAL")); err == nil {
+		ClickFlush = d
+	}
+
 	//Проверяем наличие адресов в переменном окружении, если их нет - берем адреса из флагов.
 	if Serv == "" {
 		Serv = *servFlag
@@ -44,4 +260,19 @@ func ParseFlag() {
 	if File == "" {
 		File = *fileFlag
 	}
+
+	if AdminToken == "" {
+		AdminToken = *adminFlag
+	}
+
+	if SecretKey == "" {
+		SecretKey = *keyFlag
+	}
+
+	//Без заданного ключа подписываем cookie случайным, выданные cookie перестанут действовать после перезапуска
+	if SecretKey == "" {
+		b := make([]byte, 32)
+		rand.Read(b)
+		SecretKey = hex.EncodeToString(b)
+	}
 }
\ No newline at end of file
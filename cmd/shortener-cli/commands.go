@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/pkg/client"
+)
+
+// shortener-cli shorten [-redirect code] url...
+func runShorten(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("shorten", flag.ExitOnError)
+	redirect := fs.Int("redirect", 0, "redirect status code: 301, 302, 307 or 308, server default if 0")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("no urls given")
+	}
+
+	type result struct {
+		URL      string `json:"url"`
+		ShortURL string `json:"short_url"`
+		Existed  bool   `json:"existed"`
+	}
+	var results []result
+	var rows [][]string
+
+	for _, u := range fs.Args() {
+		resp, err := c.Shorten(ctx, client.ShortenRequest{URL: u, RedirectCode: *redirect})
+		var exists *client.ExistsError
+		if err != nil && !errors.As(err, &exists) {
+			return fmt.Errorf("%s: %w", u, err)
+		}
+
+		r := result{URL: u, ShortURL: resp.Result, Existed: exists != nil}
+		results = append(results, r)
+		rows = append(rows, []string{r.ShortURL, r.URL, existedLabel(r.Existed)})
+	}
+
+	return out.print(results, []string{"SHORT URL", "URL", "STATUS"}, rows)
+}
+
+// shortener-cli batch [-f file] [-csv]
+func runBatch(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	file := fs.String("f", "", "input file, stdin if empty")
+	isCSV := fs.Bool("csv", false, `input is csv with "correlation_id,url" or "url" rows`)
+	fs.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var (
+		batch []client.BatchRequest
+		err   error
+	)
+	if *isCSV {
+		batch, err = readBatchCSV(in)
+	} else {
+		batch, err = readBatchLines(in)
+	}
+	if err != nil {
+		return err
+	}
+	if len(batch) == 0 {
+		return errors.New("no urls in input")
+	}
+
+	resp, err := c.ShortenBatch(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	//Сопоставляем ответ с исходными ссылками по correlation_id
+	urls := make(map[string]string, len(batch))
+	for _, b := range batch {
+		urls[b.ID] = b.URL
+	}
+	rows := make([][]string, 0, len(resp))
+	for _, r := range resp {
+		rows = append(rows, []string{r.ID, r.ShortURL, urls[r.ID]})
+	}
+
+	return out.print(resp, []string{"ID", "SHORT URL", "URL"}, rows)
+}
+
+// Читает по ссылке на строку, correlation_id - номер строки
+func readBatchLines(r io.Reader) ([]client.BatchRequest, error) {
+	var batch []client.BatchRequest
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		u := strings.TrimSpace(scanner.Text())
+		if u == "" || strings.HasPrefix(u, "#") {
+			continue
+		}
+		batch = append(batch, client.BatchRequest{ID: strconv.Itoa(line), URL: u})
+	}
+	return batch, scanner.Err()
+}
+
+// Читает CSV со строками "correlation_id,url" или "url". Строка заголовка пропускается
+func readBatchCSV(r io.Reader) ([]client.BatchRequest, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var batch []client.BatchRequest
+	for i, rec := range records {
+		if len(rec) == 0 || rec[len(rec)-1] == "" {
+			continue
+		}
+		last := rec[len(rec)-1]
+		if i == 0 && (last == "url" || last == "original_url") {
+			continue
+		}
+
+		id := strconv.Itoa(i + 1)
+		if len(rec) > 1 && rec[0] != "" {
+			id = rec[0]
+		}
+		batch = append(batch, client.BatchRequest{ID: id, URL: last})
+	}
+	return batch, nil
+}
+
+// shortener-cli resolve id|short_url...
+func runResolve(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no links given")
+	}
+
+	type result struct {
+		ID       string `json:"id"`
+		Status   int    `json:"status"`
+		Location string `json:"location"`
+	}
+	var results []result
+	var rows [][]string
+
+	for _, arg := range args {
+		id := client.LinkID(arg)
+		res, err := c.Resolve(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+
+		r := result{ID: id, Status: res.StatusCode, Location: res.Location}
+		results = append(results, r)
+		rows = append(rows, []string{r.ID, strconv.Itoa(r.Status), r.Location})
+	}
+
+	return out.print(results, []string{"ID", "STATUS", "LOCATION"}, rows)
+}
+
+// shortener-cli list
+func runList(ctx context.Context, c *client.Client, out *printer, _ []string) error {
+	links, err := c.ListUserURLs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return out.print(links, []string{"SHORT URL", "URL", "CLICKS", "CREATED"}, infoRows(links))
+}
+
+// shortener-cli delete id|short_url...
+func runDelete(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no links given")
+	}
+
+	ids := make([]string, 0, len(args))
+	rows := make([][]string, 0, len(args))
+	for _, arg := range args {
+		ids = append(ids, client.LinkID(arg))
+		rows = append(rows, []string{client.LinkID(arg)})
+	}
+
+	if err := c.DeleteURLs(ctx, ids...); err != nil {
+		return err
+	}
+
+	return out.print(map[string][]string{"deleted": ids}, []string{"DELETED"}, rows)
+}
+
+// shortener-cli stats id|short_url...
+func runStats(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no links given")
+	}
+
+	links := make([]client.URLInfo, 0, len(args))
+	for _, arg := range args {
+		id := client.LinkID(arg)
+		info, err := c.Info(ctx, id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		links = append(links, *info)
+	}
+
+	return out.print(links, []string{"SHORT URL", "URL", "CLICKS", "CREATED"}, infoRows(links))
+}
+
+func infoRows(links []client.URLInfo) [][]string {
+	rows := make([][]string, 0, len(links))
+	for _, l := range links {
+		full := l.FullURL
+		if l.Disabled {
+			full += " (disabled)"
+		}
+		rows = append(rows, []string{l.ShortURL, full, strconv.Itoa(l.Clicks), l.Created.Local().Format(time.DateTime)})
+	}
+	return rows
+}
+
+func existedLabel(existed bool) string {
+	if existed {
+		return "exists"
+	}
+	return "created"
+}
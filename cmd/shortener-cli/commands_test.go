@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IgorGreusunset/shortener/pkg/client"
+)
+
+func TestReadBatchLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []client.BatchRequest
+	}{
+		{name: "simple", input: "https://a.example\nhttps://b.example\n",
+			expected: []client.BatchRequest{{ID: "1", URL: "https://a.example"}, {ID: "2", URL: "https://b.example"}}},
+		{name: "blank_lines_and_comments", input: "# links\n\nhttps://a.example\n   \nhttps://b.example",
+			expected: []client.BatchRequest{{ID: "3", URL: "https://a.example"}, {ID: "5", URL: "https://b.example"}}},
+		{name: "trims_spaces", input: "  https://a.example  \r\n",
+			expected: []client.BatchRequest{{ID: "1", URL: "https://a.example"}}},
+		{name: "empty", input: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readBatchLines(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Batch didn't match expected: got %+v want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadBatchCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []client.BatchRequest
+		wantErr  bool
+	}{
+		{name: "with_ids", input: "a1,https://a.example\nb2,https://b.example\n",
+			expected: []client.BatchRequest{{ID: "a1", URL: "https://a.example"}, {ID: "b2", URL: "https://b.example"}}},
+		{name: "url_only", input: "https://a.example\nhttps://b.example\n",
+			expected: []client.BatchRequest{{ID: "1", URL: "https://a.example"}, {ID: "2", URL: "https://b.example"}}},
+		{name: "header", input: "correlation_id,url\na1,https://a.example\n",
+			expected: []client.BatchRequest{{ID: "a1", URL: "https://a.example"}}},
+		{name: "original_url_header", input: "original_url\nhttps://a.example\n",
+			expected: []client.BatchRequest{{ID: "2", URL: "https://a.example"}}},
+		{name: "blank_lines", input: "\na1,https://a.example\n\n,\nb2, https://b.example\n",
+			expected: []client.BatchRequest{{ID: "a1", URL: "https://a.example"}, {ID: "b2", URL: "https://b.example"}}},
+		{name: "empty_id", input: ",https://a.example\n",
+			expected: []client.BatchRequest{{ID: "1", URL: "https://a.example"}}},
+		{name: "malformed_quotes", input: "a1,\"https://a.example\nb2,https://b.example\n", wantErr: true},
+		{name: "bare_quote", input: "a1,https://a.example/\"x\"\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readBatchCSV(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got batch %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Batch didn't match expected: got %+v want %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// Настройки клиента из файла конфигурации
+type cliConfig struct {
+	Server string `json:"server"`
+	//Значение cookie user_id, выданной сервером
+	Token string `json:"token,omitempty"`
+}
+
+// Путь к файлу конфигурации по умолчанию: $XDG_CONFIG_HOME/shortener-cli/config.json
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "shortener-cli.json"
+	}
+	return filepath.Join(dir, "shortener-cli", "config.json")
+}
+
+// Читает конфигурацию, отсутствующий файл не считается ошибкой
+func loadConfig(path string) (cliConfig, error) {
+	cfg := cliConfig{Server: defaultServer}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.Server == "" {
+		cfg.Server = defaultServer
+	}
+	return cfg, nil
+}
+
+// Сохраняет конфигурацию, файл доступен только владельцу, так как содержит токен
+func saveConfig(path string, cfg cliConfig) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
@@ -0,0 +1,132 @@
+// Консольный клиент API сокращателя ссылок.
+//
+//	shortener-cli [-server url] [-token value] [-config path] [-o table|json] [-gzip] <command> [args]
+//
+// Команды: shorten, batch, resolve, list, delete, stats.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/IgorGreusunset/shortener/pkg/client"
+)
+
+const usage = `Usage: shortener-cli [flags] <command> [args]
+
+Commands:
+  shorten [-redirect code] url...   create short links
+  batch [-f file] [-csv]            create links from file or stdin, one url per line or csv "id,url"
+  resolve id|short_url...           show where short links lead without following them
+  list                              list your links
+  delete id|short_url...            delete your links
+  stats id|short_url...             show link details and click counts
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// Выполняет команду и возвращает код завершения. os.Exit вызывается только в main,
+// чтобы отложенное сохранение токена выполнялось и при ошибке команды
+func run(argv []string) int {
+	flags := flag.NewFlagSet("shortener-cli", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", defaultConfigPath(), "path to config file with server and token")
+	server := flags.String("server", "", "server base url, overrides config and SHORTENER_SERVER")
+	token := flags.String("token", "", "user_id cookie value, overrides config and SHORTENER_TOKEN")
+	output := flags.String("o", "table", "output format: table or json")
+	useGzip := flags.Bool("gzip", false, "compress request bodies with gzip")
+	flags.Parse(argv)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		return fail("unknown output format %q: want table or json", *output)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return fail("reading config: %v", err)
+	}
+
+	//Приоритет настроек: флаг, переменная окружения, файл конфигурации
+	cfg.Server = firstNonEmpty(*server, os.Getenv("SHORTENER_SERVER"), cfg.Server)
+	cfg.Token = firstNonEmpty(*token, os.Getenv("SHORTENER_TOKEN"), cfg.Token)
+
+	opts := []client.Option{client.WithToken(cfg.Token)}
+	if *useGzip {
+		opts = append(opts, client.WithGzip())
+	}
+	c, err := client.New(cfg.Server, opts...)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out := newPrinter(os.Stdout, *output)
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+
+	//Сервер выдает новому пользователю cookie, сохраняем ее, чтобы следующие вызовы видели те же ссылки.
+	//Токен мог быть выдан и до ошибки команды, поэтому сохраняем его в любом случае
+	defer func() {
+		if cfg.Token == "" && c.Token() != "" {
+			cfg.Token = c.Token()
+			if err := saveConfig(*configPath, cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: saving token: %v\n", err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "new user token saved to %s\n", *configPath)
+		}
+	}()
+
+	switch cmd {
+	case "shorten":
+		err = runShorten(ctx, c, out, args)
+	case "batch":
+		err = runBatch(ctx, c, out, args)
+	case "resolve":
+		err = runResolve(ctx, c, out, args)
+	case "list":
+		err = runList(ctx, c, out, args)
+	case "delete":
+		err = runDelete(ctx, c, out, args)
+	case "stats":
+		err = runStats(ctx, c, out, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		flags.Usage()
+		return 2
+	}
+	if err != nil {
+		return fail("%s: %v", cmd, err)
+	}
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Печатает ошибку и возвращает код завершения 1
+func fail(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "shortener-cli: "+format+"\n", args...)
+	return 1
+}
@@ -1,34 +1,189 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/IgorGreusunset/shortener/cmd/config"
 	model "github.com/IgorGreusunset/shortener/internal/app"
-	"github.com/IgorGreusunset/shortener/internal/handlers"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/health"
+	"github.com/IgorGreusunset/shortener/internal/helpers"
 	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/metrics"
 	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/policy"
+	"github.com/IgorGreusunset/shortener/internal/ratelimit"
 	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+	"github.com/IgorGreusunset/shortener/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 func main() {
 
+	//Подкоманды для переноса данных между хранилищами
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		}
+	}
+
 	config.ParseFlag()
 
+	if !helpers.IsRedirectCode(config.RedirectCode) {
+		log.Fatalf("Invalid default redirect code: %d", config.RedirectCode)
+	}
+	if !middleware.IsRateLimitKey(config.RateLimitKey) {
+		log.Fatalf("Invalid rate limit key: %q, expected %s or %s", config.RateLimitKey, middleware.RateLimitByIP, middleware.RateLimitByUser)
+	}
+
 	router := chi.NewRouter()
 
-	logger.Initialize()
+	//Контекст сервиса отменяется по сигналу остановки
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	//Проверки готовности регистрируются компонентами по мере запуска
+	checks := health.NewRegistry(2 * time.Second)
+
+	if err := logger.Initialize(config.LogLevel, config.LogFormat); err != nil {
+		log.Fatalf("Error during logger initialization: %v", err)
+	}
+
+	//Трассировка: без адреса коллектора span не экспортируются, но traceparent продолжает распространяться
+	shutdownTracing, err := tracing.Init(context.Background(), config.TracingEndpoint, config.TracingInsecure, config.TracingRatio)
+	if err != nil {
+		log.Fatalf("Error during tracing initialization: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, closeDB, err := openRepository(config.File, config.DataBase)
+	if err != nil {
+		log.Fatalf("Error during storage initialization: %v", err)
+	}
+	defer closeDB()
+
+	//Замеряем операции хранилища и состояние пула соединений с БД, проверяем доступность хранилища
+	backend := "file"
+	if database, ok := db.(*storage.DBRepositoryAdapter); ok {
+		backend = "postgres"
+		checks.Register("database", health.CheckFunc(database.DB.PingContext))
+		if err := metrics.RegisterDB(database.DB); err != nil {
+			log.Fatalf("Error during registering database metrics: %v", err)
+		}
+	} else {
+		checks.Register("file", health.CheckFunc(func(context.Context) error { return db.Ping() }))
+		if database, ok := db.(*storage.Storage); ok {
+			checks.Go(ctx, "click-flush", func(ctx context.Context) { database.FlushClicks(ctx, config.ClickFlush) })
+		}
+	}
+	db = metrics.NewRepository(db, backend)
+	db = tracing.NewRepository(db, backend)
+
+	//Политика доменов перечитывает списки при их изменении
+	if config.BlocklistFile != "" || config.AllowlistFile != "" {
+		engine, err := policy.New(config.BlocklistFile, config.AllowlistFile)
+		if err != nil {
+			log.Fatalf("Error during loading domain policy: %v", err)
+		}
+		policy.Default = engine
+		checks.Go(ctx, "policy", func(ctx context.Context) { engine.Watch(ctx, config.PolicyReload) })
+	}
+
+	//Тенанты с собственными доменами, запросы на остальные домены обслуживает тенант с базовым адресом из конфигурации
+	tenants, err := tenant.NewRegistry(tenant.Default(), nil)
+	if config.TenantsFile != "" {
+		tenants, err = tenant.Load(config.TenantsFile, tenant.Default())
+	}
+	if err != nil {
+		log.Fatalf("Error during loading tenants: %v", err)
+	}
+
+	//Подключаем middlewares
+	router.Use(middleware.WithTracing)
+	router.Use(middleware.WithRequestID)
+	router.Use(middleware.WithTenant(tenants))
+	router.Use(middleware.WithMetrics)
+	router.Use(middleware.WithLogging)
+	router.Use(middleware.GzipMiddleware)
+	router.Use(middleware.WithAPIKey(db))
+	//JWT поставщика удостоверений принимаются вместо cookie сервиса, если задан файл с ключами
+	if config.JWTKeysFile != "" {
+		keys, err := auth.LoadJWKS(config.JWTKeysFile)
+		if err != nil {
+			log.Fatalf("Error during loading JWKS: %v", err)
+		}
+		router.Use(middleware.WithJWT(middleware.JWTOptions{
+			Keys:     keys,
+			Audience: config.JWTAudience,
+			Issuer:   config.JWTIssuer,
+			Leeway:   config.JWTLeeway,
+		}))
+	}
+	router.Use(middleware.WithAuth([]byte(config.SecretKey)))
+
+	//Ограничения частоты запросов отдельно для создания ссылок, переходов и ввода паролей
+	limitStore, err := rateLimitStore(ctx, config.RateLimitRedis, checks)
+	if err != nil {
+		log.Fatalf("Error during rate limit store initialization: %v", err)
+	}
+	createLimit, err := rateLimiter(limitStore, config.RateLimitCreate, "create", config.RateLimitKey)
+	if err != nil {
+		log.Fatalf("Error in create rate limit: %v", err)
+	}
+	redirectLimit, err := rateLimiter(limitStore, config.RateLimitRedirect, "redirect", config.RateLimitKey)
+	if err != nil {
+		log.Fatalf("Error in redirect rate limit: %v", err)
+	}
+	//Попытки ввода пароля ограничиваются по IP: новую cookie пользователя можно получить на каждую попытку
+	unlockLimit, err := rateLimiter(limitStore, config.RateLimitUnlock, "unlock", middleware.RateLimitByIP)
+	if err != nil {
+		log.Fatalf("Error in unlock rate limit: %v", err)
+	}
 
-	var db storage.Repository
+	registerRoutes(router, db, checks, limiters{create: createLimit, redirect: redirectLimit, unlock: unlockLimit})
 
-	if config.DataBase == "" {
-		file, err := os.OpenFile(config.File, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	server := &http.Server{Addr: config.Serv, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	//Сначала снимаем готовность, чтобы балансировщик успел убрать экземпляр, затем дожидаемся текущих запросов
+	logger.Log.Infow("Shutting down", "delay", config.ShutdownDelay, "timeout", config.ShutdownTimeout)
+	checks.SetShuttingDown()
+	time.Sleep(config.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Errorf("Error during server shutdown: %v", err)
+	}
+}
+
+// Открывает хранилище: БД, если задана строка подключения, иначе файл со ссылками
+func openRepository(filePath, dsn string) (storage.Repository, func(), error) {
+	if dsn == "" {
+		file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 		if err != nil {
-			log.Fatalf("Error during opening file with shorten urls: %v", err)
+			return nil, nil, err
 		}
 		database := storage.NewStorage(map[string]model.URL{})
 		database.SetFile(file)
@@ -40,30 +195,47 @@ func main() {
 		}
 		file.Close()
 
-		db = database
-	} else {
-		var err error
-		database, err := storage.NewDatabase(config.DataBase)
-		if err != nil {
-			log.Fatalf("Error during database connection: %v", err)
-		}
-		defer database.DB.Close()
-
-		db = database
+		//Сохраняем счетчики переходов, накопленные после последней периодической записи
+		return database, func() {
+			if err := database.Flush(); err != nil {
+				logger.Log.Errorf("Error during saving clicks: %v", err)
+			}
+		}, nil
+	}
 
+	database, err := storage.NewDatabase(dsn)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	//Подключаем middlewares
-	router.Use(middleware.WithLogging)
-	router.Use(middleware.GzipMiddleware)
+	return database, func() { database.DB.Close() }, nil
+}
 
-	router.Post(`/`, handlers.PostHandler(db))
-	router.Get(`/{id}`, handlers.GetByIDHandler(db))
-	router.Post(`/api/shorten`, handlers.APIPostHandler(db))
-	router.Get(`/ping`, handlers.PingHandler(db))
-	router.Post(`/api/shorten/batch`, handlers.BathcHandler(db))
+// Хранилище корзин ограничения частоты: Redis, если задан адрес, иначе память процесса.
+// Доступность Redis и работа очистки памяти попадают в проверки готовности
+func rateLimitStore(ctx context.Context, redisURL string, checks *health.Registry) (ratelimit.Store, error) {
+	if redisURL != "" {
+		store, err := ratelimit.NewRedisStore(redisURL)
+		if err != nil {
+			return nil, err
+		}
+		checks.Register("cache", health.CheckFunc(store.Ping))
+		return store, nil
+	}
 
-	serverAdd := config.Serv
+	store := ratelimit.NewMemoryStore()
+	checks.Go(ctx, "ratelimit-cleanup", func(ctx context.Context) { store.Cleanup(ctx, time.Minute, time.Hour) })
+	return store, nil
+}
 
-	log.Fatal(http.ListenAndServe(serverAdd, router))
+// Middleware ограничения частоты по описанию вида "20/m", пустое описание отключает ограничение
+func rateLimiter(store ratelimit.Store, spec, scope, keyBy string) (func(http.Handler) http.Handler, error) {
+	limit, ok, err := ratelimit.ParseLimit(spec)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return func(h http.Handler) http.Handler { return h }, nil
+	}
+	return middleware.RateLimit(store, limit, scope, keyBy), nil
 }
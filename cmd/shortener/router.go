@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/handlers"
+	"github.com/IgorGreusunset/shortener/internal/health"
+	"github.com/IgorGreusunset/shortener/internal/metrics"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/openapi"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tracing"
+	"github.com/go-chi/chi/v5"
+)
+
+// Ограничители частоты для групп маршрутов
+type limiters struct {
+	create   func(http.Handler) http.Handler
+	redirect func(http.Handler) http.Handler
+	unlock   func(http.Handler) http.Handler
+}
+
+// Регистрирует маршруты сервиса. Все маршруты должны быть описаны в internal/openapi/openapi.yaml,
+// это проверяет router_test.go. Маршруты пользователя требуют права доступа: по cookie выдаются
+// shorten, read и delete, ключ API получает права, заданные при его создании
+func registerRoutes(router chi.Router, db storage.Repository, checks *health.Registry, limits limiters) {
+	canShorten := middleware.RequireScope(auth.ScopeShorten)
+	canRead := middleware.RequireScope(auth.ScopeRead)
+	canDelete := middleware.RequireScope(auth.ScopeDelete)
+
+	router.With(canShorten, limits.create).Post(`/`, tracing.Handler("PostHandler", handlers.PostHandler(db)))
+
+	//Переходы по ссылкам и сведения о них публичны
+	router.With(limits.redirect).Get(`/{id}`, tracing.Handler("GetByIDHandler", handlers.GetByIDHandler(db)))
+	router.With(limits.unlock).Post(`/{id}`, tracing.Handler("UnlockHandler", handlers.UnlockHandler(db)))
+	router.Get(`/{id}+`, tracing.Handler("InfoHandler", handlers.InfoHandler(db)))
+	router.Get(`/{id}/info`, tracing.Handler("InfoHandler", handlers.InfoHandler(db)))
+	router.Get(`/{id}/qr`, tracing.Handler("QRHandler", handlers.QRHandler(db)))
+	router.Get(`/ping`, tracing.Handler("PingHandler", handlers.PingHandler(db)))
+
+	//Запросы к JSON API проверяются по спецификации OpenAPI
+	router.Group(func(r chi.Router) {
+		r.Use(openapi.Validate)
+		r.With(canShorten, limits.create).Post(`/api/shorten`, tracing.Handler("APIPostHandler", handlers.APIPostHandler(db)))
+		r.With(canRead).Get(`/api/user/urls`, tracing.Handler("UserURLsHandler", handlers.UserURLsHandler(db)))
+		r.With(canRead).Get(`/api/user/usage`, tracing.Handler("UsageHandler", handlers.UsageHandler(db)))
+		r.With(canDelete).Delete(`/api/user/urls`, tracing.Handler("DeleteUserURLsHandler", handlers.DeleteUserURLsHandler(db)))
+		r.With(canShorten, limits.create).Post(`/api/shorten/batch`, tracing.Handler("BathcHandler", handlers.BathcHandler(db)))
+		r.With(canShorten).Patch(`/api/urls/{id}`, tracing.Handler("UpdateURLHandler", handlers.UpdateURLHandler(db)))
+		r.With(canRead).Get(`/api/urls/{id}/history`, tracing.Handler("HistoryHandler", handlers.HistoryHandler(db)))
+
+		//Новый ключ получает только права запроса, поэтому отдельное право для создания не нужно
+		r.Post(`/api/keys`, tracing.Handler("CreateAPIKeyHandler", handlers.CreateAPIKeyHandler(db)))
+		r.With(canRead).Get(`/api/keys`, tracing.Handler("ListAPIKeysHandler", handlers.ListAPIKeysHandler(db)))
+		r.With(canDelete).Delete(`/api/keys/{id}`, tracing.Handler("RevokeAPIKeyHandler", handlers.RevokeAPIKeyHandler(db)))
+	})
+	router.Get(`/api/openapi.json`, openapi.Handler())
+	router.Get(`/api/docs`, openapi.DocsHandler())
+	router.Get(`/api/docs/{file}`, openapi.AssetsHandler())
+
+	router.Get(`/healthz`, health.LivenessHandler())
+	router.Get(`/readyz`, checks.ReadinessHandler())
+	router.Method(http.MethodGet, `/metrics`, metrics.Handler())
+
+	//Веб-интерфейс для работы со ссылками из браузера
+	router.Get(`/`, http.RedirectHandler(`/ui`, http.StatusFound).ServeHTTP)
+	router.With(canRead).Get(`/ui`, tracing.Handler("UIHandler", handlers.UIHandler(db)))
+	router.With(canShorten, limits.create).Post(`/ui/shorten`, tracing.Handler("UIShortenHandler", handlers.UIShortenHandler(db)))
+	router.With(canDelete).Post(`/ui/links/{id}/delete`, tracing.Handler("UIDeleteHandler", handlers.UIDeleteHandler(db)))
+
+	//API администратора подключается только при заданном токене. Доступ дает токен или ключ API с правом admin
+	if config.AdminToken != "" {
+		router.Route(`/admin`, func(r chi.Router) {
+			r.Use(middleware.AdminAuth(config.AdminToken))
+			r.Group(func(r chi.Router) {
+				r.Use(openapi.Validate)
+				r.Get(`/urls`, tracing.Handler("AdminListHandler", handlers.AdminListHandler(db)))
+				r.Get(`/urls/{id}`, tracing.Handler("AdminGetHandler", handlers.AdminGetHandler(db)))
+				r.Patch(`/urls/{id}`, tracing.Handler("AdminUpdateHandler", handlers.AdminUpdateHandler(db)))
+				r.Post(`/urls/{id}/disable`, tracing.Handler("AdminDisableHandler", handlers.AdminSetDisabledHandler(db, true)))
+				r.Post(`/urls/{id}/enable`, tracing.Handler("AdminEnableHandler", handlers.AdminSetDisabledHandler(db, false)))
+				r.Delete(`/urls/{id}`, tracing.Handler("AdminDeleteHandler", handlers.AdminDeleteHandler(db)))
+				r.Post(`/keys`, tracing.Handler("AdminCreateAPIKeyHandler", handlers.AdminCreateAPIKeyHandler(db)))
+			})
+		})
+	}
+}
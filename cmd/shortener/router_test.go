@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/health"
+	"github.com/IgorGreusunset/shortener/internal/openapi"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Каждый маршрут chi должен быть описан в спецификации, и каждая операция спецификации должна иметь маршрут
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	//Маршруты администратора регистрируются только при заданном токене
+	adminToken := config.AdminToken
+	config.AdminToken = "test-token"
+	defer func() { config.AdminToken = adminToken }()
+
+	noLimit := func(h http.Handler) http.Handler { return h }
+	router := chi.NewRouter()
+	registerRoutes(router, storage.NewStorage(map[string]model.URL{}), health.NewRegistry(time.Second), limiters{create: noLimit, redirect: noLimit, unlock: noLimit})
+
+	routes := map[string]bool{}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[method+" "+strings.TrimSuffix(route, "/")] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//chi хранит корневой маршрут как пустую строку после обрезки завершающего слеша
+	for key := range routes {
+		if strings.HasSuffix(key, " ") {
+			delete(routes, key)
+			routes[key+"/"] = true
+		}
+	}
+
+	spec, err := openapi.Spec()
+	if err != nil {
+		t.Fatalf("Invalid OpenAPI document: %v", err)
+	}
+	operations := map[string]bool{}
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			operations[method+" "+path] = true
+		}
+	}
+
+	for _, route := range sortedKeys(routes) {
+		if !operations[route] {
+			t.Errorf("Route %s is not described in openapi.yaml", route)
+		}
+	}
+	for _, op := range sortedKeys(operations) {
+		if !routes[op] {
+			t.Errorf("Operation %s from openapi.yaml has no route", op)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
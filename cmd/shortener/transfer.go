@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/transfer"
+)
+
+// Общие флаги подкоманд export и import для выбора хранилища
+func storageFlags(fs *flag.FlagSet) (file, dsn, format *string) {
+	file = fs.String("f", envOr("FILE_STORAGE_PATH", "./short_url.json"), "path to file with short urls")
+	dsn = fs.String("d", os.Getenv("DATABASE_DSN"), "string for database connection")
+	format = fs.String("format", transfer.FormatJSONL, "data format: jsonl or csv")
+	return file, dsn, format
+}
+
+// shortener export [-f file | -d dsn] [-format jsonl|csv] [-o output]
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	file, dsn, format := storageFlags(fs)
+	out := fs.String("o", "", "output file, stdout if empty")
+	fs.Parse(args)
+
+	if err := logger.Initialize(envOr("LOG_LEVEL", "info"), envOr("LOG_FORMAT", "console")); err != nil {
+		log.Fatalf("Error during logger initialization: %v", err)
+	}
+
+	db, closeDB, err := openRepository(*file, *dsn)
+	if err != nil {
+		log.Fatalf("Error during storage initialization: %v", err)
+	}
+	defer closeDB()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Error during creating output file: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count, err := transfer.Export(context.Background(), db, w, *format)
+	if err != nil {
+		log.Fatalf("Error during export: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d records\n", count)
+}
+
+// shortener import [-f file | -d dsn] [-format jsonl|csv] [-i input]
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	file, dsn, format := storageFlags(fs)
+	in := fs.String("i", "", "input file, stdin if empty")
+	fs.Parse(args)
+
+	if err := logger.Initialize(envOr("LOG_LEVEL", "info"), envOr("LOG_FORMAT", "console")); err != nil {
+		log.Fatalf("Error during logger initialization: %v", err)
+	}
+
+	db, closeDB, err := openRepository(*file, *dsn)
+	if err != nil {
+		log.Fatalf("Error during storage initialization: %v", err)
+	}
+	defer closeDB()
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			log.Fatalf("Error during opening input file: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	report, err := transfer.Import(context.Background(), db, r, *format)
+	if report != nil {
+		for _, c := range report.Conflicts {
+			fmt.Fprintf(os.Stderr, "conflict: %s -> %s: %s\n", c.Record.ID, c.Record.FullURL, c.Reason)
+		}
+		fmt.Fprintf(os.Stderr, "imported %d records, %d conflicts\n", report.Imported, len(report.Conflicts))
+	}
+	if err != nil {
+		log.Fatalf("Error during import: %v", err)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -1,9 +1,30 @@
 package model
 
+import "time"
+
 type URL struct {
-	UUID int `json:"uuid"`
-	ID      string `json:"short_url"`
-	FullURL string	`json:"original_url"`
+	UUID     int       `json:"uuid"`
+	ID       string    `json:"short_url"`
+	FullURL  string    `json:"original_url"`
+	UserID   string    `json:"user_id,omitempty"`
+	Created  time.Time `json:"created"`
+	Disabled bool      `json:"disabled,omitempty"`
+	//Код ответа при переходе по ссылке, 0 - код по умолчанию из конфигурации
+	RedirectCode int `json:"redirect_code,omitempty"`
+	Clicks       int `json:"clicks"`
+	//Тенант, в пространстве которого уникален ID, пустой у тенанта по умолчанию
+	Tenant string `json:"tenant,omitempty"`
+	//bcrypt-хеш пароля, пустой у ссылок без пароля
+	PasswordHash string `json:"password_hash,omitempty"`
+	//Допустимое число переходов, 0 - без ограничения
+	MaxClicks int `json:"max_clicks,omitempty"`
+}
+
+// Проверяет, что ссылку можно выдать повторно на ту же полную ссылку. Ссылки с лимитом переходов
+// выдаются получателям по отдельности, а защищенная паролем ссылка не должна подменяться открытой,
+// поэтому для них всегда создается новая ссылка
+func (u URL) Shareable() bool {
+	return u.MaxClicks == 0 && u.PasswordHash == ""
 }
 
 // Фабричный метод для создания экземпляра URL структуры
@@ -11,31 +32,192 @@ func NewURL(id, full string) *URL {
 	return &URL{
 		ID:      id,
 		FullURL: full,
+		Created: time.Now(),
 	}
 }
 
 type APIPostRequest struct {
-	URL string `json:"url"`
+	URL          string `json:"url"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	//Вернуть в ответе QR-код короткой ссылки
+	QR bool `json:"qr,omitempty"`
+	//Пароль, который нужно ввести перед переходом по ссылке
+	Password string `json:"password,omitempty"`
+	//Допустимое число переходов, например 1 для одноразовой ссылки
+	MaxClicks int `json:"max_clicks,omitempty"`
 }
 
 type APIPostResponse struct {
 	Result string `json:"result"`
+	//PNG-изображение QR-кода в base64
+	QR string `json:"qr,omitempty"`
 }
 
 func NewAPIPostResponse(result string) *APIPostResponse {
 	return &APIPostResponse{Result: result}
 }
 
+// Тело ответа с ошибкой. RequestID совпадает с заголовком X-Request-ID и позволяет найти запрос в логах сервера
+type APIError struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
 type APIBatchRequest struct {
-	ID string `json:"correlation_id"`
-	URL string `json:"original_url"`
+	ID           string `json:"correlation_id"`
+	URL          string `json:"original_url"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	MaxClicks    int    `json:"max_clicks,omitempty"`
 }
 
 type APIBatchResponse struct {
-	ID string `json:"correlation_id"`
+	ID       string `json:"correlation_id"`
 	ShortURL string `json:"short_url"`
 }
 
 func NewAPIBatchResponse(id, shortURL string) *APIBatchResponse {
 	return &APIBatchResponse{ID: id, ShortURL: shortURL}
 }
+
+// Запрос на изменение полной ссылки
+type UpdateURLRequest struct {
+	URL string `json:"original_url"`
+}
+
+// Запись истории изменений полной ссылки
+type Revision struct {
+	Tenant  string    `json:"tenant,omitempty"`
+	ID      string    `json:"short_url"`
+	OldURL  string    `json:"old_url"`
+	NewURL  string    `json:"new_url"`
+	Actor   string    `json:"actor"`
+	Changed time.Time `json:"changed"`
+}
+
+// Сведения о короткой ссылке для страницы предпросмотра
+type URLInfo struct {
+	ShortURL string    `json:"short_url"`
+	FullURL  string    `json:"original_url"`
+	Created  time.Time `json:"created"`
+	Clicks   int       `json:"clicks"`
+	Disabled bool      `json:"disabled,omitempty"`
+	//Переход по ссылке требует пароля
+	Protected bool `json:"protected,omitempty"`
+	MaxClicks int  `json:"max_clicks,omitempty"`
+}
+
+func NewURLInfo(shortURL string, u URL) *URLInfo {
+	return &URLInfo{
+		ShortURL:  shortURL,
+		FullURL:   u.FullURL,
+		Created:   u.Created,
+		Clicks:    u.Clicks,
+		Disabled:  u.Disabled,
+		Protected: u.PasswordHash != "",
+		MaxClicks: u.MaxClicks,
+	}
+}
+
+// Запись о ссылке для API администратора. Вместо хеша пароля содержит только признак защищенной ссылки,
+// чтобы хеш нельзя было перебирать вне ограничения попыток ввода пароля
+type AdminURL struct {
+	UUID         int       `json:"uuid"`
+	ID           string    `json:"short_url"`
+	FullURL      string    `json:"original_url"`
+	UserID       string    `json:"user_id,omitempty"`
+	Created      time.Time `json:"created"`
+	Disabled     bool      `json:"disabled,omitempty"`
+	RedirectCode int       `json:"redirect_code,omitempty"`
+	Clicks       int       `json:"clicks"`
+	Tenant       string    `json:"tenant,omitempty"`
+	Protected    bool      `json:"protected,omitempty"`
+	MaxClicks    int       `json:"max_clicks,omitempty"`
+}
+
+func NewAdminURL(u URL) AdminURL {
+	return AdminURL{
+		UUID:         u.UUID,
+		ID:           u.ID,
+		FullURL:      u.FullURL,
+		UserID:       u.UserID,
+		Created:      u.Created,
+		Disabled:     u.Disabled,
+		RedirectCode: u.RedirectCode,
+		Clicks:       u.Clicks,
+		Tenant:       u.Tenant,
+		Protected:    u.PasswordHash != "",
+		MaxClicks:    u.MaxClicks,
+	}
+}
+
+// Ключ API машинного клиента. Сам ключ не хранится, только его хеш
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Hash   string `json:"hash"`
+	//Начало ключа, по которому владелец может его узнать
+	Prefix  string     `json:"prefix"`
+	Scopes  []string   `json:"scopes"`
+	Created time.Time  `json:"created"`
+	Revoked *time.Time `json:"revoked,omitempty"`
+}
+
+// Запрос на создание ключа API. UserID учитывается только в API администратора
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	UserID string   `json:"user_id,omitempty"`
+}
+
+// Сведения о ключе API без хеша
+type APIKeyInfo struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Prefix  string     `json:"prefix"`
+	Scopes  []string   `json:"scopes"`
+	Created time.Time  `json:"created"`
+	Revoked *time.Time `json:"revoked,omitempty"`
+}
+
+func NewAPIKeyInfo(k APIKey) *APIKeyInfo {
+	return &APIKeyInfo{
+		ID:      k.ID,
+		Name:    k.Name,
+		Prefix:  k.Prefix,
+		Scopes:  k.Scopes,
+		Created: k.Created,
+		Revoked: k.Revoked,
+	}
+}
+
+// Ответ на создание ключа API. Ключ возвращается только один раз
+type APIKeyResponse struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// Использование ссылок пользователем: активные ссылки и созданные за сутки
+type Usage struct {
+	Active  int
+	Created int
+}
+
+// Квоты, которые хранилище проверяет вместе с созданием ссылок. Нулевой лимит означает отсутствие ограничения.
+// Созданные пользователем ссылки считаются за сутки Day и не возвращаются в квоту при удалении
+type Quota struct {
+	MaxLinks    int
+	UserID      string
+	ActiveLimit int
+	DailyLimit  int
+	Day         time.Time
+}
+
+// Использование и квоты пользователя. Нулевой лимит означает отсутствие ограничения
+type UserUsage struct {
+	Active      int       `json:"active"`
+	ActiveLimit int       `json:"active_limit"`
+	Daily       int       `json:"daily"`
+	DailyLimit  int       `json:"daily_limit"`
+	Reset       time.Time `json:"reset"`
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// Префикс ключей API, по нему ключ отличается от других bearer-токенов
+const APIKeyPrefix = "shk_"
+
+// Права доступа ключей API
+const (
+	ScopeShorten = "shorten"
+	ScopeRead    = "read"
+	ScopeDelete  = "delete"
+	ScopeAdmin   = "admin"
+)
+
+// Все известные права доступа
+var Scopes = []string{ScopeShorten, ScopeRead, ScopeDelete, ScopeAdmin}
+
+// Права пользователя, идентифицированного по cookie
+var UserScopes = []string{ScopeShorten, ScopeRead, ScopeDelete}
+
+type scopesKey struct{}
+
+type apiKeyKey struct{}
+
+// Генерирует новый ключ API и его идентификатор
+func NewAPIKey() (key, id string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	key = APIKeyPrefix + hex.EncodeToString(b)
+
+	b = make([]byte, 8)
+	rand.Read(b)
+	return key, hex.EncodeToString(b)
+}
+
+// Хеш ключа API для хранения и поиска. Ключ случайный и длинный, поэтому медленный хеш не нужен
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Начало ключа, которое показывается в списке ключей
+func APIKeyDisplayPrefix(key string) string {
+	n := len(APIKeyPrefix) + 8
+	if len(key) < n {
+		return key
+	}
+	return key[:n]
+}
+
+// Проверяет, что ключ имеет формат ключа API
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// Проверяет, что право доступа известно
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// Кладет права доступа в контекст запроса
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// Достает права доступа из контекста запроса
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesKey{}).([]string)
+	return scopes
+}
+
+// Проверяет, что у запроса есть право доступа
+func HasScope(ctx context.Context, scope string) bool {
+	return slices.Contains(ScopesFromContext(ctx), scope)
+}
+
+// Кладет идентификатор ключа API, которым выполнен запрос, в контекст
+func WithAPIKey(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, keyID)
+}
+
+// Достает идентификатор ключа API из контекста запроса
+func APIKeyFromContext(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(apiKeyKey{}).(string)
+	return keyID, ok && keyID != ""
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Имя cookie с подписанным идентификатором пользователя
+const CookieName = "user_id"
+
+// Способы аутентификации запроса
+const (
+	MethodCookie = "cookie"
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+type ctxKey struct{}
+
+type methodKey struct{}
+
+// Генерирует новый случайный идентификатор пользователя
+func NewUserID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Подписывает идентификатор пользователя ключом, результат используется как значение cookie
+func Sign(userID string, key []byte) string {
+	return userID + "." + hex.EncodeToString(mac(userID, key))
+}
+
+// Проверяет подпись значения cookie и возвращает идентификатор пользователя
+func Verify(value string, key []byte) (string, bool) {
+	userID, sign, ok := strings.Cut(value, ".")
+	if !ok || userID == "" {
+		return "", false
+	}
+
+	got, err := hex.DecodeString(sign)
+	if err != nil {
+		return "", false
+	}
+
+	if !hmac.Equal(got, mac(userID, key)) {
+		return "", false
+	}
+	return userID, true
+}
+
+// Токен пользователя для заданной цели, например защиты форм. Зависит только от ключа, цели и пользователя
+func Token(purpose, userID string, key []byte) string {
+	return hex.EncodeToString(mac(purpose+":"+userID, key))
+}
+
+// Кладет идентификатор пользователя в контекст запроса
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, userID)
+}
+
+// Достает идентификатор пользователя из контекста запроса
+func UserFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(ctxKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// Кладет способ аутентификации в контекст запроса
+func WithMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodKey{}, method)
+}
+
+// Достает способ аутентификации из контекста запроса
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodKey{}).(string)
+	return method
+}
+
+// Проверяет, что запрос аутентифицирован bearer-токеном: ключом API или JWT
+func IsBearer(ctx context.Context) bool {
+	method := MethodFromContext(ctx)
+	return method == MethodAPIKey || method == MethodJWT
+}
+
+func mac(userID string, key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(userID))
+	return h.Sum(nil)
+}
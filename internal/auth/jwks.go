@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Ошибка при отсутствии подходящего ключа для проверки подписи
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// Ключ проверки подписи JWT из набора JWKS
+type JWK struct {
+	ID  string
+	Alg string
+	//[]byte для HS256, *rsa.PublicKey для RS256
+	Key any
+}
+
+// Набор ключей поставщика удостоверений
+type JWKS struct {
+	Keys []JWK
+}
+
+// Формат ключа в JSON Web Key Set, RFC 7517
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Загружает набор ключей из файла JWKS
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data)
+}
+
+// Разбирает набор ключей JWKS. Поддерживаются ключи oct (HS256) и RSA (RS256),
+// ключи других типов и ключи шифрования пропускаются
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	result := &JWKS{}
+	for i, raw := range set.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		var key any
+		switch raw.Kty {
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(raw.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("key %d: invalid oct key", i)
+			}
+			key = secret
+		case "RSA":
+			pub, err := rsaPublicKey(raw.N, raw.E)
+			if err != nil {
+				return nil, fmt.Errorf("key %d: %w", i, err)
+			}
+			key = pub
+		default:
+			continue
+		}
+
+		result.Keys = append(result.Keys, JWK{ID: raw.Kid, Alg: raw.Alg, Key: key})
+	}
+
+	if len(result.Keys) == 0 {
+		return nil, errors.New("no signing keys in JWKS")
+	}
+	return result, nil
+}
+
+// Находит ключ для проверки подписи алгоритмом alg. Тип ключа должен соответствовать алгоритму,
+// поэтому открытый ключ RSA нельзя использовать как секрет HS256. Без kid ключ выбирается, только если он единственный
+func (s *JWKS) Key(kid, alg string) (any, error) {
+	var found []any
+	for _, k := range s.Keys {
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		if k.Alg != "" && k.Alg != alg {
+			continue
+		}
+
+		switch k.Key.(type) {
+		case []byte:
+			if alg == "HS256" {
+				found = append(found, k.Key)
+			}
+		case *rsa.PublicKey:
+			if alg == "RS256" {
+				found = append(found, k.Key)
+			}
+		}
+	}
+
+	if len(found) != 1 {
+		return nil, ErrKeyNotFound
+	}
+	return found[0], nil
+}
+
+// Собирает открытый ключ RSA из модуля и экспоненты в base64url
+func rsaPublicKey(n, e string) (*rsa.PublicKey, error) {
+	nb, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil || len(nb) == 0 {
+		return nil, errors.New("invalid RSA modulus")
+	}
+	eb, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil || len(eb) == 0 || len(eb) > 4 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+
+	exp := 0
+	for _, b := range eb {
+		exp = exp<<8 | int(b)
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nb), E: exp}, nil
+}
@@ -3,16 +3,45 @@ package compress
 import (
 	"compress/gzip"
 	"io"
+	"mime"
 	"net/http"
 )
 
+// Сжимает ответ, если обработчик отдает контент одного из типов types. Решение о сжатии
+// принимается при первом вызове WriteHeader или Write, когда обработчик уже выставил заголовки
 type CompressWriter struct {
 	w http.ResponseWriter
+	types []string
+	//Создается только при сжатии ответа
 	gw *gzip.Writer
+	started bool
+	//Счетчики байт до и после сжатия
+	in  int
+	out *countingWriter
 }
 
-func NewCompressWrite (w http.ResponseWriter) *CompressWriter {
-	return &CompressWriter{w: w, gw: gzip.NewWriter(w),}
+func NewCompressWrite (w http.ResponseWriter, types ...string) *CompressWriter {
+	return &CompressWriter{w: w, types: types, out: &countingWriter{w: w}}
+}
+
+// Считает байты, записанные в ответ после сжатия
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// Возвращает степень сжатия: отношение исходного размера к сжатому. Имеет смысл после Close
+func (c *CompressWriter) Ratio() (float64, bool) {
+	if c.gw == nil || c.in == 0 || c.out.n == 0 {
+		return 0, false
+	}
+	return float64(c.in) / float64(c.out.n), true
 }
 
 func (c *CompressWriter) Header() http.Header {
@@ -20,17 +49,61 @@ func (c *CompressWriter) Header() http.Header {
 }
 
 func (c *CompressWriter) Write(p []byte) (int, error) {
-	return c.gw.Write(p)
+	if !c.started {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.gw == nil {
+		return c.w.Write(p)
+	}
+
+	n, err := c.gw.Write(p)
+	c.in += n
+	return n, err
 }
 
 func (c *CompressWriter) WriteHeader(statusCode int) {
-	if statusCode < 300 {
-		c.w.Header().Set("Content-Encoding", "gzip")
+	if c.started {
+		return
+	}
+	c.started = true
+
+	if c.compressible(statusCode) {
+		h := c.w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Add("Vary", "Accept-Encoding")
+		//Длина исходного тела не совпадает с длиной сжатого
+		h.Del("Content-Length")
+		c.gw = gzip.NewWriter(c.out)
 	}
 	c.w.WriteHeader(statusCode)
 }
 
+// Проверяет, что ответ с таким статусом и заголовками нужно сжимать
+func (c *CompressWriter) compressible(statusCode int) bool {
+	if statusCode < 200 || statusCode >= 300 || statusCode == http.StatusNoContent {
+		return false
+	}
+	h := c.w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, t := range c.types {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *CompressWriter) Close() error {
+	if c.gw == nil {
+		return nil
+	}
 	return c.gw.Close()
 }
 
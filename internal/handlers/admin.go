@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler для поиска ссылок по подстроке полной ссылки (параметр q)
+func AdminListHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		urls, err := db.List(req.Context(), req.URL.Query().Get("q"))
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to list urls", http.StatusInternalServerError)
+			return
+		}
+		result := make([]model.AdminURL, 0, len(urls))
+		for _, u := range urls {
+			result = append(result, model.NewAdminURL(u))
+		}
+
+		writeJSON(res, req, http.StatusOK, result)
+	}
+}
+
+// Handler для получения всех данных о ссылке по ID
+func AdminGetHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		u, ok := db.GetByID(req.Context(), chi.URLParam(req, "id"))
+		if !ok {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSON(res, req, http.StatusOK, model.NewAdminURL(u))
+	}
+}
+
+// Handler для изменения полной ссылки
+func AdminUpdateHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		u, ok := db.GetByID(req.Context(), chi.URLParam(req, "id"))
+		if !ok {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+
+		var body model.UpdateURLRequest
+		if err := json.NewDecoder(limitBody(res, req)).Decode(&body); err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyError(res, err)
+				return
+			}
+			http.Error(res, "Failed decoding request body", http.StatusBadRequest)
+			return
+		}
+
+		fullURL, err := normalizeURL(body.URL)
+		if err != nil {
+			writeURLError(res, err)
+			return
+		}
+
+		if updated, ok := retarget(res, req, db, u.ID, fullURL, "admin"); ok {
+			writeJSON(res, req, http.StatusOK, model.NewAdminURL(updated))
+		}
+	}
+}
+
+// Handler для включения и отключения ссылки
+func AdminSetDisabledHandler(db storage.Repository, disabled bool) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		u, ok := db.GetByID(req.Context(), chi.URLParam(req, "id"))
+		if !ok {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+
+		u.Disabled = disabled
+		adminSave(res, req, db, &u)
+	}
+}
+
+// Handler для безвозвратного удаления ссылки
+func AdminDeleteHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		err := db.Delete(req.Context(), chi.URLParam(req, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to delete url", http.StatusInternalServerError)
+			return
+		}
+
+		res.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Сохраняет измененную запись и записывает ее в ответ
+func adminSave(res http.ResponseWriter, req *http.Request, db storage.Repository, u *model.URL) {
+	err := db.Update(req.Context(), u)
+	if err != nil {
+		var uee *storage.URLExistsError
+		switch {
+		case errors.As(err, &uee):
+			http.Error(res, "Original URL already stored as "+uee.ShortURL, http.StatusConflict)
+		case errors.Is(err, storage.ErrNotFound):
+			http.Error(res, "Not found", http.StatusNotFound)
+		default:
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to update url", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(res, req, http.StatusOK, model.NewAdminURL(*u))
+}
+
+// Сериализует v в тело ответа с заданным статусом
+func writeJSON(res http.ResponseWriter, req *http.Request, status int, v any) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		writeAPIError(res, req, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	res.Write(response)
+}
+
+// Записывает ошибку в JSON с идентификатором запроса, чтобы клиент мог сослаться на него
+func writeAPIError(res http.ResponseWriter, req *http.Request, status int, msg string) {
+	//Структура из строк всегда сериализуется без ошибки
+	response, _ := json.Marshal(model.APIError{Error: msg, RequestID: middleware.RequestID(req.Context())})
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	res.Write(response)
+}
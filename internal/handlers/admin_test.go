@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/mock/gomock"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	//Ссылка защищена паролем, хеш не должен попадать в ответы
+	const hash = "$2a$10$7EqJtq98hPqEX7fNZaFWoOhi5BWX4Z3wLRi3VfQ2sP6cP1yTb9Fq2"
+	protected := model.URL{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", PasswordHash: hash}
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(protected, true).AnyTimes()
+	m.EXPECT().List(gomock.Any(), "yandex").Return([]model.URL{protected}, nil)
+	m.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.EXPECT().Retarget(gomock.Any(), "U8rtGB25", "https://mail.ru/", "admin").Return(model.URL{ID: "U8rtGB25", FullURL: "https://mail.ru/", PasswordHash: hash}, nil)
+	m.EXPECT().Delete(gomock.Any(), "U8rtGB25").Return(nil)
+	m.EXPECT().Delete(gomock.Any(), "yyokley").Return(storage.ErrNotFound)
+
+	router := chi.NewRouter()
+	router.Route(`/admin`, func(r chi.Router) {
+		r.Use(middleware.AdminAuth("secret"))
+		r.Get(`/urls`, AdminListHandler(m))
+		r.Get(`/urls/{id}`, AdminGetHandler(m))
+		r.Patch(`/urls/{id}`, AdminUpdateHandler(m))
+		r.Post(`/urls/{id}/disable`, AdminSetDisabledHandler(m, true))
+		r.Delete(`/urls/{id}`, AdminDeleteHandler(m))
+	})
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "no_token",
+			method:       http.MethodGet,
+			path:         "/admin/urls/U8rtGB25",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "wrong_token",
+			method:       http.MethodGet,
+			path:         "/admin/urls/U8rtGB25",
+			token:        "wrong",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			path:         "/admin/urls/U8rtGB25",
+			token:        "secret",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "list",
+			method:       http.MethodGet,
+			path:         "/admin/urls?q=yandex",
+			token:        "secret",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "update",
+			method:       http.MethodPatch,
+			path:         "/admin/urls/U8rtGB25",
+			token:        "secret",
+			body:         `{"original_url":"https://mail.ru/"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "update_not_url",
+			method:       http.MethodPatch,
+			path:         "/admin/urls/U8rtGB25",
+			token:        "secret",
+			body:         `{"original_url":"not url"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "disable",
+			method:       http.MethodPost,
+			path:         "/admin/urls/U8rtGB25/disable",
+			token:        "secret",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "delete",
+			method:       http.MethodDelete,
+			path:         "/admin/urls/U8rtGB25",
+			token:        "secret",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "delete_not_found",
+			method:       http.MethodDelete,
+			path:         "/admin/urls/yyokley",
+			token:        "secret",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := resty.New().R()
+			req.Method = tt.method
+			req.URL = srv.URL + tt.path
+			if tt.token != "" {
+				req.SetAuthToken(tt.token)
+			}
+			if tt.body != "" {
+				req.SetBody(tt.body)
+			}
+
+			resp, err := req.Send()
+			if err != nil {
+				t.Errorf("error making HTTP request: %v", err)
+			}
+
+			if resp.StatusCode() != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", resp.StatusCode(), tt.expectedCode)
+			}
+			if resp.StatusCode() != http.StatusOK {
+				return
+			}
+			body := resp.String()
+			if strings.Contains(body, "password_hash") || strings.Contains(body, hash) {
+				t.Errorf("Response reveals password hash: %s", body)
+			}
+			if !strings.Contains(body, `"protected":true`) {
+				t.Errorf("Response doesn't mark link as protected: %s", body)
+			}
+		})
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+
+	w := httptest.NewRecorder()
+	middleware.WithRequestID(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		//Канал не сериализуется в JSON
+		writeJSON(res, req, http.StatusOK, make(chan int))
+	})).ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusInternalServerError)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"request_id":"req-42"`) {
+		t.Errorf("Error body doesn't contain request id: %s", body)
+	}
+}
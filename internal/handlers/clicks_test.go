@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMaxClicks(t *testing.T) {
+	db := storage.NewStorage(map[string]model.URL{})
+
+	redirect := func(id string) int {
+		req := httptest.NewRequest(http.MethodGet, "/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		w := httptest.NewRecorder()
+		GetByIDHandler(db)(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		createCode    int
+		expectedCodes []int
+	}{
+		{name: "one_time", body: `{"url":"https://invite.example/1","max_clicks":1}`, createCode: http.StatusCreated,
+			expectedCodes: []int{http.StatusTemporaryRedirect, http.StatusGone, http.StatusGone}},
+		{name: "three_clicks", body: `{"url":"https://invite.example/3","max_clicks":3}`, createCode: http.StatusCreated,
+			expectedCodes: []int{http.StatusTemporaryRedirect, http.StatusTemporaryRedirect, http.StatusTemporaryRedirect, http.StatusGone}},
+		{name: "unlimited", body: `{"url":"https://invite.example/0"}`, createCode: http.StatusCreated,
+			expectedCodes: []int{http.StatusTemporaryRedirect, http.StatusTemporaryRedirect}},
+		{name: "negative", body: `{"url":"https://invite.example/-1","max_clicks":-1}`, createCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			APIPostHandler(db)(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body)))
+			if w.Code != tt.createCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.createCode)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+
+			var resp model.APIPostResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			id := resp.Result[strings.LastIndex(resp.Result, "/")+1:]
+
+			for i, expected := range tt.expectedCodes {
+				if got := redirect(id); got != expected {
+					t.Errorf("Response code of click %d didn't match expected: got %d want %d", i+1, got, expected)
+				}
+			}
+		})
+	}
+
+	//Одноразовые ссылки на один адрес выдаются по отдельности, обычные - повторно
+	t.Run("same_destination", func(t *testing.T) {
+		create := func(body string) (int, string) {
+			w := httptest.NewRecorder()
+			APIPostHandler(db)(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body)))
+
+			var resp model.APIPostResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return w.Code, resp.Result[strings.LastIndex(resp.Result, "/")+1:]
+		}
+
+		var ids []string
+		for i := 0; i < 2; i++ {
+			code, id := create(`{"url":"https://invite.example/team","max_clicks":1}`)
+			if code != http.StatusCreated {
+				t.Fatalf("Response code didn't match expected: got %d want %d", code, http.StatusCreated)
+			}
+			ids = append(ids, id)
+		}
+		if ids[0] == ids[1] {
+			t.Fatalf("One-time links share short url %s", ids[0])
+		}
+		for _, id := range ids {
+			if got := redirect(id); got != http.StatusTemporaryRedirect {
+				t.Errorf("Response code of first click on %s didn't match expected: got %d want %d", id, got, http.StatusTemporaryRedirect)
+			}
+		}
+
+		_, shared := create(`{"url":"https://invite.example/team"}`)
+		code, again := create(`{"url":"https://invite.example/team"}`)
+		if code != http.StatusConflict || again != shared {
+			t.Errorf("Repeated link didn't match expected: got %d %s want %d %s", code, again, http.StatusConflict, shared)
+		}
+	})
+
+	//Параллельные переходы по одноразовой ссылке перенаправляют ровно один раз
+	t.Run("concurrent", func(t *testing.T) {
+		u := model.NewURL("once", "https://invite.example/once")
+		u.MaxClicks = 1
+		db.Create(context.Background(), u)
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			redirects int
+		)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if redirect("once") == http.StatusTemporaryRedirect {
+					mu.Lock()
+					redirects++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if redirects != 1 {
+			t.Errorf("One-time link redirected %d times", redirects)
+		}
+	})
+}
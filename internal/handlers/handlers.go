@@ -4,88 +4,263 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"net/url"
+	"strconv"
 
 	"github.com/IgorGreusunset/shortener/cmd/config"
 	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
 	"github.com/IgorGreusunset/shortener/internal/helpers"
 	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/metrics"
+	"github.com/IgorGreusunset/shortener/internal/policy"
 	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/urlnorm"
 	"github.com/go-chi/chi/v5"
 )
 
-// Handler для обработки Post-запроса на запись новой URL структуры в хранилище
+// Handler для обработки Post-запроса на запись новой URL структуры в хранилище.
+// Принимает ссылку сырым текстом или формой, отвечает текстом, JSON или HTML в зависимости от Accept
 func PostHandler(db storage.Repository) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
-		reqBody, err := io.ReadAll(req.Body)
+		form, err := readPostForm(res, req)
 		if err != nil {
-			res.WriteHeader(http.StatusBadRequest)
+			writeBodyError(res, err)
 			return
 		}
 
 		defer req.Body.Close()
 
-		//Проверяем, что в теле запроса корректный URL-адрес
-		_, err = url.ParseRequestURI(string(reqBody))
+		//Проверяем, что в теле запроса корректный URL-адрес, и приводим его к каноническому виду
+		fullURL, err := normalizeURL(form.URL)
 		if err != nil {
+			writeURLError(res, err)
+			return
+		}
+
+		//Код перенаправления можно передать параметром запроса или полем формы
+		redirectCode, ok := parseRedirectCode(form.RedirectCode)
+		if !ok {
 			res.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		//Генерируем ID для короткой ссылки
-		id := helpers.Generate()
+		maxClicks, ok := parseMaxClicks(form.MaxClicks)
+		if !ok {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
 
-		//Создаем новый экземпляр URL структуры и записываем его в хранилище
-		urlToAdd := model.NewURL(id, string(reqBody))
-		ctx := context.Background()
-		if err := db.Create(ctx, urlToAdd); err != nil {
+		//Создаем короткую ссылку и записываем ее в хранилище
+		urlToAdd := model.NewURL("", fullURL)
+		urlToAdd.RedirectCode = redirectCode
+		urlToAdd.MaxClicks = maxClicks
+		id, err := shorten(req.Context(), db, urlToAdd)
+		if err != nil {
 			var uee *storage.URLExistsError
 			if errors.As(err, &uee) {
-				res.Header().Set("Content-type", "text/plain")
-				res.WriteHeader(http.StatusConflict)
-				resBody := config.Base + `/` + uee.ShortURL
-				if _, err := res.Write([]byte(resBody)); err != nil {
-					log.Printf("Error writing response: %v\n", err)
-					http.Error(res, "Internal server error", http.StatusInternalServerError)
-				}
+				writeShortURL(res, req, http.StatusConflict, shortURL(req.Context(), uee.ShortURL))
+				return
+			}
+			if isQuotaError(err) {
+				writeQuotaError(res, req, err)
 				return
 			}
-			logger.Log.Debugln(err)
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		//Записываем заголовок и тело ответа
-		res.Header().Set("Content-type", "text/plain")
-		res.WriteHeader(http.StatusCreated)
-		resBody := config.Base + `/` + id
-		if _, err := res.Write([]byte(resBody)); err != nil {
-			log.Printf("Error writing response: %v\n", err)
-			http.Error(res, "Internal server error", http.StatusInternalServerError)
-		}
+		writeShortURL(res, req, http.StatusCreated, shortURL(req.Context(), id))
 	}
 }
 
+// Сохраняет подготовленную запись как новую короткую ссылку текущего пользователя и возвращает ее ID.
+// Если полная ссылка уже сохранена, возвращает *storage.URLExistsError, при исчерпании квоты - *quotaError
+func shorten(ctx context.Context, db storage.Repository, urlToAdd *model.URL) (string, error) {
+	urlToAdd.ID = helpers.Generate()
+	urlToAdd.UserID, _ = auth.UserFromContext(ctx)
+
+	urls := []model.URL{*urlToAdd}
+	if err := createWithQuota(ctx, db, urls); err != nil {
+		return "", err
+	}
+	*urlToAdd = urls[0]
+	return urlToAdd.ID, nil
+}
+
 // Handler для обработки Get-запроса на получение ссылки по ID
 func GetByIDHandler(db storage.Repository) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		//Получаем ID из запроса и ищем по нему URL структуру в хранилище
 		short := chi.URLParam(req, "id")
 
-		fullURL, ok := db.GetByID(short)
+		fullURL, ok := db.GetByID(req.Context(), short)
+		metrics.ObserveRedirect(ok)
 
 		if !ok {
 			res.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
+		//Отключенные администратором и исчерпавшие лимит переходов ссылки не перенаправляются
+		if fullURL.Disabled || clicksExhausted(fullURL) {
+			res.WriteHeader(http.StatusGone)
+			return
+		}
+
+		//Ссылка могла попасть в черный список уже после создания
+		if err := policy.Default.Check(fullURL.FullURL); err != nil {
+			http.Error(res, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		//Защищенная ссылка перенаправляет только после ввода пароля, см. UnlockHandler
+		if fullURL.PasswordHash != "" && !unlocked(req, fullURL) {
+			renderPassword(res, req, http.StatusOK, passwordPage{ID: short})
+			return
+		}
+
+		if !registerClick(res, req, db, fullURL) {
+			return
+		}
+
 		//Записываем заголовок ответа
 		res.Header().Set("Location", fullURL.FullURL)
-		res.WriteHeader(http.StatusTemporaryRedirect)
+		res.WriteHeader(redirectCode(fullURL))
+	}
+}
+
+// Проверяет и нормализует полную ссылку по настройкам из конфигурации, затем проверяет ее по политике доменов
+func normalizeURL(raw string) (string, error) {
+	if len(raw) > config.MaxURLLength {
+		return "", errURLTooLong
+	}
+
+	fullURL, err := urlnorm.Normalize(raw, urlnorm.Options{
+		AllowedSchemes: config.AllowedSchemes,
+		Normalize:      config.NormalizeURLs,
+		SortQuery:      config.SortQuery,
+	})
+	if err != nil {
+		return "", err
 	}
+
+	if err := policy.Default.Check(fullURL); err != nil {
+		return "", err
+	}
+	return fullURL, nil
+}
+
+var errURLTooLong = errors.New("url too long")
+
+// Код ответа для ошибки проверки ссылки: запрет политикой, превышение длины или некорректный адрес
+func urlErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, policy.ErrBlocked) || errors.Is(err, policy.ErrNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, errURLTooLong):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// Записывает ответ для ошибки проверки ссылки. Некорректный адрес отдается пустым ответом, как и раньше
+func writeURLError(res http.ResponseWriter, err error) {
+	status := urlErrorStatus(err)
+	switch status {
+	case http.StatusBadRequest:
+		res.WriteHeader(status)
+	case http.StatusRequestEntityTooLarge:
+		http.Error(res, fmt.Sprintf("URL too long: limit is %d characters", config.MaxURLLength), status)
+	default:
+		http.Error(res, err.Error(), status)
+	}
+}
+
+// Ограничивает размер тела запроса значением из конфигурации
+func limitBody(res http.ResponseWriter, req *http.Request) io.Reader {
+	req.Body = http.MaxBytesReader(res, req.Body, config.MaxBodySize)
+	return req.Body
+}
+
+func isBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+// Записывает ответ для ошибки чтения тела запроса
+func writeBodyError(res http.ResponseWriter, err error) {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		http.Error(res, fmt.Sprintf("Request body too large: limit is %d bytes", mbe.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	res.WriteHeader(http.StatusBadRequest)
+}
+
+// Возвращает код перенаправления для ссылки: заданный при создании или код по умолчанию
+func redirectCode(u model.URL) int {
+	if u.RedirectCode != 0 {
+		return u.RedirectCode
+	}
+	if config.RedirectCode != 0 {
+		return config.RedirectCode
+	}
+	return http.StatusTemporaryRedirect
+}
+
+// Проверяет, что ссылка исчерпала допустимое число переходов по данным записи.
+// Окончательно лимит проверяется атомарно при учете перехода
+func clicksExhausted(u model.URL) bool {
+	return u.MaxClicks > 0 && u.Clicks >= u.MaxClicks
+}
+
+// Учитывает переход по ссылке и возвращает false, если ответ уже записан. Ошибка учета не мешает
+// перенаправлению, кроме ссылок с лимитом переходов: для них учет и есть проверка лимита
+func registerClick(res http.ResponseWriter, req *http.Request, db storage.Repository, u model.URL) bool {
+	err := db.RegisterClick(req.Context(), u.ID)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, storage.ErrClicksExhausted) {
+		res.WriteHeader(http.StatusGone)
+		return false
+	}
+
+	logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+	if u.MaxClicks > 0 {
+		http.Error(res, "Failed to register click", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// Разбирает лимит переходов из параметра запроса, пустое значение означает ссылку без лимита
+func parseMaxClicks(value string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Разбирает код перенаправления из параметра запроса, пустое значение означает код по умолчанию
+func parseRedirectCode(value string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || !helpers.IsRedirectCode(code) {
+		return 0, false
+	}
+	return code, true
 }
 
 // Handler для обработки json-запроса на создание новой ссылки
@@ -93,51 +268,83 @@ func APIPostHandler(db storage.Repository) http.HandlerFunc {
 	return func(res http.ResponseWriter, req *http.Request) {
 		//Получаем данные для создания URL модели из запроса
 		var urlFromRequest model.APIPostRequest
-		dec := json.NewDecoder(req.Body)
+		dec := json.NewDecoder(limitBody(res, req))
 		if err := dec.Decode(&urlFromRequest); err != nil {
-			logger.Log.Debugln("error", err)
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			if isBodyTooLarge(err) {
+				writeBodyError(res, err)
+				return
+			}
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		//Проверяем корректость адреса в теле запроса
-		_, err := url.ParseRequestURI(urlFromRequest.URL)
+		fullURL, err := normalizeURL(urlFromRequest.URL)
 		if err != nil {
+			writeURLError(res, err)
+			return
+		}
+
+		if urlFromRequest.RedirectCode != 0 && !helpers.IsRedirectCode(urlFromRequest.RedirectCode) {
 			res.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		id := helpers.Generate()
+		if urlFromRequest.MaxClicks < 0 {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		passwordHash, err := hashPassword(urlFromRequest.Password)
+		if err != nil {
+			writePasswordError(res, req, err)
+			return
+		}
 
 		//Создаем модель и записываем в storage
-		urlToAdd := model.NewURL(id, urlFromRequest.URL)
-		ctx := context.Background()
-		if err := db.Create(ctx, urlToAdd); err != nil {
+		urlToAdd := model.NewURL("", fullURL)
+		urlToAdd.RedirectCode = urlFromRequest.RedirectCode
+		urlToAdd.PasswordHash = passwordHash
+		urlToAdd.MaxClicks = urlFromRequest.MaxClicks
+		id, err := shorten(req.Context(), db, urlToAdd)
+		if err != nil {
 			var uee *storage.URLExistsError
 			if errors.As(err, &uee) {
 				res.Header().Set("Content-type", "application/json")
 				res.WriteHeader(http.StatusConflict)
-				result := config.Base + `/` + uee.ShortURL
+				result := shortURL(req.Context(), uee.ShortURL)
 				resp := model.NewAPIPostResponse(result)
+				if urlFromRequest.QR {
+					resp.QR = inlineQR(req.Context(), result)
+				}
 				response, err := json.Marshal(resp)
 				if err != nil {
-					logger.Log.Debugln("error", err)
+					logger.FromContext(req.Context()).Debugw("request failed", "error", err)
 					res.WriteHeader(http.StatusInternalServerError)
 					return
 				}
 				res.Write(response)
 				return
 			}
+			if isQuotaError(err) {
+				writeQuotaError(res, req, err)
+				return
+			}
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		//Формируем и сериализируем тело ответа
-		result := config.Base + `/` + id
+		result := shortURL(req.Context(), id)
 		resp := model.NewAPIPostResponse(result)
+		if urlFromRequest.QR {
+			resp.QR = inlineQR(req.Context(), result)
+		}
 		response, err := json.Marshal(resp)
 		if err != nil {
-			logger.Log.Debugln("error", err)
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -171,25 +378,59 @@ func BathcHandler(db storage.Repository) http.HandlerFunc {
 		)
 
 		//Десериализуем тело запроса в слайс
-		err := json.NewDecoder(req.Body).Decode(&requests)
+		err := json.NewDecoder(limitBody(res, req)).Decode(&requests)
 		if err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyError(res, err)
+				return
+			}
 			http.Error(res, "Failed decoding request body", http.StatusBadRequest)
+			return
+		}
+
+		metrics.BatchSize.Observe(float64(len(requests)))
+
+		if len(requests) > config.MaxBatchSize {
+			http.Error(res, fmt.Sprintf("Batch too large: limit is %d urls", config.MaxBatchSize), http.StatusRequestEntityTooLarge)
+			return
 		}
 
+		userID, _ := auth.UserFromContext(req.Context())
+
 		//Проходим по слайсу и для каждого элемента создаем model.URL и подготавливаем модель для ответа
 		for _, r := range requests {
+			if r.RedirectCode != 0 && !helpers.IsRedirectCode(r.RedirectCode) {
+				http.Error(res, "Invalid redirect code for "+r.ID, http.StatusBadRequest)
+				return
+			}
+			if r.MaxClicks < 0 {
+				http.Error(res, "Invalid max_clicks for "+r.ID, http.StatusBadRequest)
+				return
+			}
+			fullURL, err := normalizeURL(r.URL)
+			if err != nil {
+				http.Error(res, "Invalid url for "+r.ID+": "+err.Error(), urlErrorStatus(err))
+				return
+			}
 			sh := helpers.Generate()
-			url := model.NewURL(sh, r.URL)
+			url := model.NewURL(sh, fullURL)
+			url.UserID = userID
+			url.RedirectCode = r.RedirectCode
+			url.MaxClicks = r.MaxClicks
 			urls = append(urls, *url)
-			w := model.NewAPIBatchResponse(r.ID, config.Base+`/`+sh)
+			w := model.NewAPIBatchResponse(r.ID, shortURL(req.Context(), sh))
 			shorts = append(shorts, *w)
 		}
 
-		ctx := context.Background()
-		//Сохраняем ссылки в хранилище
+		//Сохраняем ссылки в хранилище с проверкой квот
 		if len(urls) != 0 {
-			if err = db.CreateBatch(ctx, urls); err != nil {
+			if err = createWithQuota(req.Context(), db, urls); err != nil {
+				if isQuotaError(err) {
+					writeQuotaError(res, req, err)
+					return
+				}
 				http.Error(res, "Failed to save urls in db", http.StatusInternalServerError)
+				return
 			}
 		}
 		res.Header().Set("Content-Type", "application/json")
@@ -27,7 +27,7 @@ func TestPostHandler(t *testing.T) {
 
 	m := mocks.NewMockRepository(ctrl)
 
-	m.EXPECT().Create(context.Background(), gomock.Any()).Return(nil)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 	srv := httptest.NewServer(PostHandler(m))
 
@@ -93,10 +93,12 @@ func TestGetByIDHandler(t *testing.T) {
 
 	m := mocks.NewMockRepository(ctrl)
 	gomock.InOrder(
-		m.EXPECT().GetByID("U8rtGB25").Return(model.URL{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/"}, true),
-		m.EXPECT().GetByID("g7RETf01").Return(model.URL{ID: "g7RETf01", FullURL: "https://mail.ru/"}, true),
-		m.EXPECT().GetByID("yyokley").Return(model.URL{}, false),
+		m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(model.URL{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/"}, true),
+		m.EXPECT().GetByID(gomock.Any(), "g7RETf01").Return(model.URL{ID: "g7RETf01", FullURL: "https://mail.ru/"}, true),
+		m.EXPECT().GetByID(gomock.Any(), "yyokley").Return(model.URL{}, false),
+		m.EXPECT().GetByID(gomock.Any(), "pErm3011").Return(model.URL{ID: "pErm3011", FullURL: "https://mail.ru/", RedirectCode: http.StatusMovedPermanently}, true),
 	)
+	m.EXPECT().RegisterClick(gomock.Any(), gomock.Any()).Return(nil).Times(3)
 
 	srv := httptest.NewServer(GetByIDHandler(m))
 	defer srv.Close()
@@ -128,6 +130,13 @@ func TestGetByIDHandler(t *testing.T) {
 			requestID:    "yyokley",
 			expectedCode: http.StatusBadRequest,
 		},
+		{
+			name:             "permanent_redirect",
+			method:           http.MethodGet,
+			requestID:        "pErm3011",
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "https://mail.ru/",
+		},
 	}
 
 	for _, test := range tests {
@@ -163,7 +172,7 @@ func TestAPIPostHandler(t *testing.T) {
 
 	m := mocks.NewMockRepository(ctrl)
 
-	m.EXPECT().Create(context.Background(), gomock.Any()).Return(nil)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
 	srv := httptest.NewServer(APIPostHandler(m))
 
@@ -221,10 +230,7 @@ func TestBatchHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	m := mocks.NewMockRepository(ctrl)
-	gomock.InOrder(
-		m.EXPECT().Create(context.Background(), gomock.Any()).Return(nil).MaxTimes(2),
-		m.EXPECT().CreateBatch(context.Background(), gomock.Any()).Return(nil).Times(1),
-	)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Len(2), gomock.Any()).Return(nil).Times(1)
 	body := []model.APIBatchRequest{
 		model.APIBatchRequest{ID: "1", URL: "https://mail.ru/"},
 		model.APIBatchRequest{ID: "2", URL: "https://practicum.yandex.ru/"},
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var infoTemplate = template.Must(template.New("info").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.ShortURL}}</title>
+</head>
+<body>
+<h1>{{.ShortURL}}</h1>
+{{if .Protected}}<p>Ссылка защищена паролем</p>{{else}}<p>Ссылка ведет на: <a href="{{.FullURL}}" rel="noopener noreferrer">{{.FullURL}}</a></p>{{end}}
+{{if .Disabled}}<p><strong>Ссылка отключена</strong></p>{{end}}
+<p>Создана: {{.Created.Format "2006-01-02 15:04:05"}}</p>
+<p>Переходов: {{.Clicks}}{{if .MaxClicks}} из {{.MaxClicks}}{{end}}</p>
+</body>
+</html>
+`))
+
+// Handler для страницы предпросмотра ссылки без перенаправления. Отдает JSON, если клиент предпочитает его HTML
+func InfoHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		short := chi.URLParam(req, "id")
+
+		u, ok := db.GetByID(req.Context(), short)
+		if !ok {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+
+		info := model.NewURLInfo(shortURL(req.Context(), short), u)
+		//Адрес защищенной ссылки не раскрывается без пароля
+		if info.Protected {
+			info.FullURL = ""
+		}
+
+		if negotiate(req.Header.Get("Accept"), contentTypeHTML, contentTypeJSON) == contentTypeJSON {
+			writeJSON(res, req, http.StatusOK, info)
+			return
+		}
+
+		res.Header().Set("Content-Type", contentTypeHTML)
+		res.WriteHeader(http.StatusOK)
+		if err := infoTemplate.Execute(res, info); err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		}
+	}
+}
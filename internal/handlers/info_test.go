@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+)
+
+func TestInfoHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(model.URL{
+		ID:      "U8rtGB25",
+		FullURL: "https://practicum.yandex.ru/",
+		Created: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Clicks:  42,
+	}, true).AnyTimes()
+	m.EXPECT().GetByID(gomock.Any(), "yyokley").Return(model.URL{}, false)
+
+	tests := []struct {
+		name            string
+		requestID       string
+		accept          string
+		expectedCode    int
+		expectedContent string
+		expectedBody    string
+	}{
+		{
+			name:            "html",
+			requestID:       "U8rtGB25",
+			expectedCode:    http.StatusOK,
+			expectedContent: "text/html",
+			expectedBody:    "https://practicum.yandex.ru/",
+		},
+		{
+			name:            "json",
+			requestID:       "U8rtGB25",
+			accept:          "application/json",
+			expectedCode:    http.StatusOK,
+			expectedContent: "application/json",
+			expectedBody:    `"clicks":42`,
+		},
+		{
+			name:            "json_lower_quality",
+			requestID:       "U8rtGB25",
+			accept:          "application/json;q=0.5, text/html",
+			expectedCode:    http.StatusOK,
+			expectedContent: "text/html",
+			expectedBody:    "https://practicum.yandex.ru/",
+		},
+		{
+			name:            "json_preferred",
+			requestID:       "U8rtGB25",
+			accept:          "text/html;q=0.1, application/json",
+			expectedCode:    http.StatusOK,
+			expectedContent: "application/json",
+			expectedBody:    `"clicks":42`,
+		},
+		{
+			name:            "not_found",
+			requestID:       "yyokley",
+			expectedCode:    http.StatusNotFound,
+			expectedContent: "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.requestID+"+", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			cntx := chi.NewRouteContext()
+			cntx.URLParams.Add("id", tt.requestID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, cntx))
+
+			w := httptest.NewRecorder()
+			InfoHandler(m)(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+
+			if res.Header.Get("Content-Type") != tt.expectedContent {
+				t.Errorf("Response content-type didn't match expected: got %v want %v", res.Header.Get("Content-Type"), tt.expectedContent)
+			}
+
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Response body doesn't contain %q: %s", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler для создания ключа API текущего пользователя. Ключ получает только права, которые есть у самого запроса
+func CreateAPIKeyHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, ok := decodeAPIKeyRequest(res, req)
+		if !ok {
+			return
+		}
+
+		granted := auth.ScopesFromContext(req.Context())
+		for _, scope := range body.Scopes {
+			if !slices.Contains(granted, scope) {
+				http.Error(res, "Forbidden: can't grant scope "+scope, http.StatusForbidden)
+				return
+			}
+		}
+
+		createAPIKey(res, req, db, userID, body)
+	}
+}
+
+// Handler для создания ключа API любому пользователю с любыми правами
+func AdminCreateAPIKeyHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		body, ok := decodeAPIKeyRequest(res, req)
+		if !ok {
+			return
+		}
+		if body.UserID == "" {
+			http.Error(res, "user_id is required", http.StatusBadRequest)
+			return
+		}
+
+		createAPIKey(res, req, db, body.UserID, body)
+	}
+}
+
+// Handler для получения ключей API текущего пользователя, включая отозванные
+func ListAPIKeysHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		keys, err := db.ListAPIKeys(req.Context(), userID)
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to list API keys", http.StatusInternalServerError)
+			return
+		}
+
+		infos := make([]*model.APIKeyInfo, 0, len(keys))
+		for _, k := range keys {
+			infos = append(infos, model.NewAPIKeyInfo(k))
+		}
+
+		writeJSON(res, req, http.StatusOK, infos)
+	}
+}
+
+// Handler для отзыва ключа API текущего пользователя
+func RevokeAPIKeyHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		err := db.RevokeAPIKey(req.Context(), userID, chi.URLParam(req, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to revoke API key", http.StatusInternalServerError)
+			return
+		}
+
+		res.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Читает и проверяет запрос на создание ключа. При ошибке сам записывает ответ
+func decodeAPIKeyRequest(res http.ResponseWriter, req *http.Request) (model.APIKeyRequest, bool) {
+	var body model.APIKeyRequest
+	if err := json.NewDecoder(limitBody(res, req)).Decode(&body); err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyError(res, err)
+			return body, false
+		}
+		http.Error(res, "Failed decoding request body", http.StatusBadRequest)
+		return body, false
+	}
+
+	if len(body.Scopes) == 0 {
+		http.Error(res, "At least one scope is required", http.StatusBadRequest)
+		return body, false
+	}
+	for _, scope := range body.Scopes {
+		if !auth.ValidScope(scope) {
+			http.Error(res, "Unknown scope "+scope, http.StatusBadRequest)
+			return body, false
+		}
+	}
+	slices.Sort(body.Scopes)
+	body.Scopes = slices.Compact(body.Scopes)
+
+	return body, true
+}
+
+// Генерирует ключ, сохраняет его хеш и возвращает сам ключ в ответе
+func createAPIKey(res http.ResponseWriter, req *http.Request, db storage.Repository, userID string, body model.APIKeyRequest) {
+	key, id := auth.NewAPIKey()
+	record := model.APIKey{
+		ID:      id,
+		UserID:  userID,
+		Name:    body.Name,
+		Hash:    auth.HashAPIKey(key),
+		Prefix:  auth.APIKeyDisplayPrefix(key),
+		Scopes:  body.Scopes,
+		Created: time.Now(),
+	}
+
+	if err := db.CreateAPIKey(req.Context(), &record); err != nil {
+		logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		http.Error(res, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(res, req, http.StatusCreated, &model.APIKeyResponse{APIKeyInfo: *model.NewAPIKeyInfo(record), Key: key})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+)
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	var saved model.APIKey
+	m.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, key *model.APIKey) error {
+		saved = *key
+		return nil
+	}).AnyTimes()
+
+	tests := []struct {
+		name         string
+		admin        bool
+		scopes       []string
+		body         string
+		expectedCode int
+		expectedUser string
+	}{
+		{
+			name:         "user_scopes",
+			scopes:       auth.UserScopes,
+			body:         `{"name":"ci","scopes":["shorten","read","shorten"]}`,
+			expectedCode: http.StatusCreated,
+			expectedUser: "owner",
+		},
+		{
+			name:         "escalation",
+			scopes:       auth.UserScopes,
+			body:         `{"scopes":["admin"]}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "key_without_scope",
+			scopes:       []string{auth.ScopeRead},
+			body:         `{"scopes":["shorten"]}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "unknown_scope",
+			scopes:       auth.UserScopes,
+			body:         `{"scopes":["write"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no_scopes",
+			scopes:       auth.UserScopes,
+			body:         `{"name":"ci"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "admin",
+			admin:        true,
+			body:         `{"user_id":"robot","scopes":["admin"]}`,
+			expectedCode: http.StatusCreated,
+			expectedUser: "robot",
+		},
+		{
+			name:         "admin_without_user",
+			admin:        true,
+			body:         `{"scopes":["admin"]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved = model.APIKey{}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/keys", strings.NewReader(tt.body))
+			ctx := auth.WithUser(req.Context(), "owner")
+			req = req.WithContext(auth.WithScopes(ctx, tt.scopes))
+
+			w := httptest.NewRecorder()
+			if tt.admin {
+				AdminCreateAPIKeyHandler(m)(w, req)
+			} else {
+				CreateAPIKeyHandler(m)(w, req)
+			}
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusCreated {
+				return
+			}
+
+			var body model.APIKeyResponse
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			//Хранится только хеш выданного ключа
+			if saved.Hash != auth.HashAPIKey(body.Key) || strings.Contains(saved.Hash, body.Key) {
+				t.Errorf("Stored hash didn't match returned key")
+			}
+			if saved.UserID != tt.expectedUser {
+				t.Errorf("Key owner didn't match expected: got %s want %s", saved.UserID, tt.expectedUser)
+			}
+			if !strings.HasPrefix(body.Key, body.Prefix) {
+				t.Errorf("Key prefix %s didn't match key", body.Prefix)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().RevokeAPIKey(gomock.Any(), "owner", "a1b2").Return(nil)
+	m.EXPECT().RevokeAPIKey(gomock.Any(), "stranger", "a1b2").Return(storage.ErrNotFound)
+
+	tests := []struct {
+		name         string
+		userID       string
+		expectedCode int
+	}{
+		{
+			name:         "owner",
+			userID:       "owner",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "not_owner",
+			userID:       "stranger",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/keys/a1b2", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "a1b2")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(auth.WithUser(ctx, tt.userID))
+
+			w := httptest.NewRecorder()
+			RevokeAPIKeyHandler(m)(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+		})
+	}
+}
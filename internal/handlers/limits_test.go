@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+func TestRequestLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+
+	defer func(body int64, batch, length int) {
+		config.MaxBodySize, config.MaxBatchSize, config.MaxURLLength = body, batch, length
+	}(config.MaxBodySize, config.MaxBatchSize, config.MaxURLLength)
+	config.MaxBodySize = 256
+	config.MaxBatchSize = 2
+	config.MaxURLLength = 64
+
+	//Архив небольшого размера, который распаковывается в тело больше лимита
+	var bomb bytes.Buffer
+	gw := gzip.NewWriter(&bomb)
+	gw.Write([]byte("https://mail.ru/" + strings.Repeat("a", 10000)))
+	gw.Close()
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		body     string
+		encoding string
+	}{
+		{
+			name:    "body_too_large",
+			handler: PostHandler(m),
+			body:    "https://mail.ru/" + strings.Repeat("a", 300),
+		},
+		{
+			name:    "url_too_long",
+			handler: APIPostHandler(m),
+			body:    `{"url":"https://mail.ru/` + strings.Repeat("a", 64) + `"}`,
+		},
+		{
+			name:    "batch_too_large",
+			handler: BathcHandler(m),
+			body:    `[{"correlation_id":"1","original_url":"https://mail.ru/"},{"correlation_id":"2","original_url":"https://ya.ru/"},{"correlation_id":"3","original_url":"https://vk.com/"}]`,
+		},
+		{
+			name:     "gzip_bomb",
+			handler:  middleware.GzipMiddleware(PostHandler(m)),
+			body:     bomb.String(),
+			encoding: "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("Response code didn't match expected: got %d want %d", w.Code, http.StatusRequestEntityTooLarge)
+			}
+			if w.Body.Len() == 0 {
+				t.Errorf("Expected error message in response body")
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+)
+
+const (
+	contentTypeText = "text/plain"
+	contentTypeJSON = "application/json"
+	contentTypeHTML = "text/html"
+)
+
+// Фрагмент страницы с короткой ссылкой для HTML-форм
+var shortURLTemplate = template.Must(template.New("short").Parse(
+	`<p class="short-url"><a href="{{.}}">{{.}}</a></p>` + "\n"))
+
+// Данные формы создания ссылки
+type postForm struct {
+	URL          string
+	RedirectCode string
+	MaxClicks    string
+}
+
+// Читает ссылку из тела запроса: сырой текст, url-encoded или multipart форма с полем url.
+// Код перенаправления и лимит переходов берутся из полей формы redirect_code и max_clicks или из параметров запроса.
+// Тело url-encoded запроса без поля url считается сырой ссылкой: так curl -d отправляет текст
+func readPostForm(res http.ResponseWriter, req *http.Request) (postForm, error) {
+	query := req.URL.Query()
+	form := postForm{RedirectCode: query.Get("redirect_code"), MaxClicks: query.Get("max_clicks")}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	if mediaType == "multipart/form-data" {
+		limitBody(res, req)
+		if err := req.ParseMultipartForm(config.MaxBodySize); err != nil {
+			return postForm{}, err
+		}
+		return formValues(form, req.MultipartForm.Value), nil
+	}
+
+	body, err := io.ReadAll(limitBody(res, req))
+	if err != nil {
+		return postForm{}, err
+	}
+	form.URL = string(body)
+
+	if mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(form.URL); err == nil && values.Has("url") {
+			return formValues(form, values), nil
+		}
+	}
+	return form, nil
+}
+
+// Заполняет форму из полей url, redirect_code и max_clicks
+func formValues(form postForm, values url.Values) postForm {
+	form.URL = strings.TrimSpace(values.Get("url"))
+	if code := values.Get("redirect_code"); code != "" {
+		form.RedirectCode = code
+	}
+	if n := values.Get("max_clicks"); n != "" {
+		form.MaxClicks = n
+	}
+	return form
+}
+
+// Выбирает формат ответа по заголовку Accept с учетом q-факторов. Первый из offers используется по умолчанию,
+// в том числе для */* и пустого заголовка
+func negotiate(accept string, offers ...string) string {
+	best, bestQ := offers[0], 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		for _, offer := range offers {
+			if q > bestQ && matchMediaType(mediaType, offer) {
+				best, bestQ = offer, q
+			}
+		}
+	}
+	return best
+}
+
+// Проверяет, подходит ли offer под диапазон типов из Accept: точное совпадение, type/* или */*
+func matchMediaType(mediaRange, offer string) bool {
+	if mediaRange == "*/*" || mediaRange == offer {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange, "/*")
+	return ok && strings.HasPrefix(offer, prefix+"/")
+}
+
+// Записывает короткую ссылку в формате, запрошенном клиентом: текст, JSON или фрагмент HTML
+func writeShortURL(res http.ResponseWriter, req *http.Request, status int, shortURL string) {
+	switch negotiate(req.Header.Get("Accept"), contentTypeText, contentTypeJSON, contentTypeHTML) {
+	case contentTypeJSON:
+		writeJSON(res, req, status, model.NewAPIPostResponse(shortURL))
+	case contentTypeHTML:
+		res.Header().Set("Content-Type", contentTypeHTML)
+		res.WriteHeader(status)
+		if err := shortURLTemplate.Execute(res, shortURL); err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		}
+	default:
+		res.Header().Set("Content-type", contentTypeText)
+		res.WriteHeader(status)
+		if _, err := res.Write([]byte(shortURL)); err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		}
+	}
+}
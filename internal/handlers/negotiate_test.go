@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+func TestPostHandlerNegotiation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	mw.WriteField("url", "https://mail.ru/")
+	mw.WriteField("redirect_code", "301")
+	mw.Close()
+
+	tests := []struct {
+		name            string
+		contentType     string
+		accept          string
+		body            string
+		expectedCode    int
+		expectedContent string
+		expectedBody    string
+	}{
+		{
+			name:            "raw_text",
+			contentType:     "text/plain",
+			body:            "https://mail.ru/",
+			expectedCode:    http.StatusCreated,
+			expectedContent: "text/plain",
+		},
+		{
+			name:            "curl_raw_as_form",
+			contentType:     "application/x-www-form-urlencoded",
+			body:            "https://mail.ru/",
+			expectedCode:    http.StatusCreated,
+			expectedContent: "text/plain",
+		},
+		{
+			name:            "form_json",
+			contentType:     "application/x-www-form-urlencoded",
+			accept:          "application/json",
+			body:            "url=https%3A%2F%2Fmail.ru%2F",
+			expectedCode:    http.StatusCreated,
+			expectedContent: "application/json",
+			expectedBody:    `"result":`,
+		},
+		{
+			name:            "form_bad_redirect_code",
+			contentType:     "application/x-www-form-urlencoded",
+			body:            "url=https%3A%2F%2Fmail.ru%2F&redirect_code=200",
+			expectedCode:    http.StatusBadRequest,
+			expectedContent: "",
+		},
+		{
+			name:            "multipart_html",
+			contentType:     mw.FormDataContentType(),
+			accept:          "text/html,application/xhtml+xml,*/*;q=0.8",
+			body:            multipartBody.String(),
+			expectedCode:    http.StatusCreated,
+			expectedContent: "text/html",
+			expectedBody:    `<a href="`,
+		},
+		{
+			name:            "accept_q_values",
+			contentType:     "text/plain",
+			accept:          "application/json;q=0.5, text/*",
+			body:            "https://mail.ru/",
+			expectedCode:    http.StatusCreated,
+			expectedContent: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			w := httptest.NewRecorder()
+			PostHandler(m)(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+			if res.Header.Get("Content-Type") != tt.expectedContent {
+				t.Errorf("Response content-type didn't match expected: got %v want %v", res.Header.Get("Content-Type"), tt.expectedContent)
+			}
+
+			body, _ := io.ReadAll(res.Body)
+			if !strings.Contains(string(body), tt.expectedBody) {
+				t.Errorf("Response body %q doesn't contain %q", body, tt.expectedBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/policy"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var passwordTemplate = template.Must(template.ParseFS(templatesFS, "templates/password.html"))
+
+// Данные страницы ввода пароля
+type passwordPage struct {
+	ID       string
+	ShortURL string
+	Error    string
+}
+
+// bcrypt учитывает только первые 72 байта пароля, более длинные пароли отклоняем
+const maxPasswordLength = 72
+
+var errPasswordTooLong = errors.New("password too long: limit is 72 bytes")
+
+// Хеширует пароль ссылки. Пустой пароль означает ссылку без пароля
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > maxPasswordLength {
+		return "", errPasswordTooLong
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Записывает ответ для ошибки хеширования пароля
+func writePasswordError(res http.ResponseWriter, req *http.Request, err error) {
+	if errors.Is(err, errPasswordTooLong) {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+	http.Error(res, "Failed to hash password", http.StatusInternalServerError)
+}
+
+// Handler для ввода пароля защищенной ссылки. При верном пароле выдает cookie разблокировки
+// на config.UnlockTTL и перенаправляет на полную ссылку. Частота попыток ограничивается в роутере
+func UnlockHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		limitBody(res, req)
+		if err := req.ParseForm(); err != nil {
+			writeBodyError(res, err)
+			return
+		}
+
+		short := chi.URLParam(req, "id")
+		u, ok := db.GetByID(req.Context(), short)
+		if !ok {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if u.Disabled || clicksExhausted(u) {
+			res.WriteHeader(http.StatusGone)
+			return
+		}
+
+		if err := policy.Default.Check(u.FullURL); err != nil {
+			http.Error(res, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if u.PasswordHash != "" {
+			password := req.PostForm.Get("password")
+			if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+				logger.FromContext(req.Context()).Infow("wrong link password", "id", short)
+				renderPassword(res, req, http.StatusForbidden, passwordPage{ID: short, Error: "Неверный пароль"})
+				return
+			}
+
+			expires := time.Now().Add(config.UnlockTTL)
+			http.SetCookie(res, &http.Cookie{
+				Name:     unlockCookieName(short),
+				Value:    unlockToken(u, expires.Unix()),
+				Path:     "/" + short,
+				Expires:  expires,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		if !registerClick(res, req, db, u) {
+			return
+		}
+
+		http.Redirect(res, req, u.FullURL, http.StatusSeeOther)
+	}
+}
+
+// Отдает страницу ввода пароля. Страница не кешируется, чтобы после ввода пароля браузер не показал ее снова
+func renderPassword(res http.ResponseWriter, req *http.Request, status int, page passwordPage) {
+	page.ShortURL = shortURL(req.Context(), page.ID)
+
+	res.Header().Set("Content-Type", "text/html")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(status)
+	if err := passwordTemplate.Execute(res, page); err != nil {
+		logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+	}
+}
+
+// Имя cookie разблокировки ссылки. Cookie отправляется только по пути ссылки
+func unlockCookieName(id string) string {
+	return "unlock_" + id
+}
+
+// Значение cookie разблокировки: время истечения и подпись ссылки вместе с хешем пароля,
+// поэтому смена пароля отзывает выданные cookie
+func unlockToken(u model.URL, expires int64) string {
+	exp := strconv.FormatInt(expires, 10)
+	subject := u.Tenant + "/" + u.ID + ":" + exp + ":" + u.PasswordHash
+	return exp + "." + auth.Token("unlock", subject, []byte(config.SecretKey))
+}
+
+// Проверяет, что запрос несет действующую cookie разблокировки ссылки
+func unlocked(req *http.Request, u model.URL) bool {
+	c, err := req.Cookie(unlockCookieName(u.ID))
+	if err != nil {
+		return false
+	}
+
+	exp, _, ok := strings.Cut(c.Value, ".")
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if !ok || err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(unlockToken(u, expires))) == 1
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestProtectedLink(t *testing.T) {
+	hash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	u := model.URL{ID: "locked", FullURL: "https://docs.example/internal", PasswordHash: hash}
+	db := storage.NewStorage(map[string]model.URL{"locked": u})
+
+	request := func(method, id string, body io.Reader, cookies ...*http.Cookie) *http.Request {
+		req := httptest.NewRequest(method, "/"+id, body)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+	form := func(password string) io.Reader {
+		return strings.NewReader(url.Values{"password": {password}}.Encode())
+	}
+
+	//Верный пароль выдает cookie, с которой ссылка перенаправляет сразу
+	w := httptest.NewRecorder()
+	UnlockHandler(db)(w, request(http.MethodPost, "locked", form("secret")))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusSeeOther)
+	}
+	if got := w.Header().Get("Location"); got != u.FullURL {
+		t.Errorf("Location didn't match expected: got %s want %s", got, u.FullURL)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected one unlock cookie, got %d", len(cookies))
+	}
+	unlock := cookies[0]
+
+	tampered := *unlock
+	tampered.Value = "9999999999" + unlock.Value[strings.Index(unlock.Value, "."):]
+	expired := &http.Cookie{Name: unlock.Name, Value: unlockToken(u, time.Now().Add(-time.Minute).Unix())}
+
+	tests := []struct {
+		name         string
+		req          *http.Request
+		handler      http.HandlerFunc
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "prompt", req: request(http.MethodGet, "locked", nil), handler: GetByIDHandler(db),
+			expectedCode: http.StatusOK, expectedBody: `name="password"`},
+		{name: "wrong_password", req: request(http.MethodPost, "locked", form("guess")), handler: UnlockHandler(db),
+			expectedCode: http.StatusForbidden, expectedBody: "Неверный пароль"},
+		{name: "unlocked", req: request(http.MethodGet, "locked", nil, unlock), handler: GetByIDHandler(db),
+			expectedCode: http.StatusTemporaryRedirect},
+		{name: "tampered_cookie", req: request(http.MethodGet, "locked", nil, &tampered), handler: GetByIDHandler(db),
+			expectedCode: http.StatusOK, expectedBody: `name="password"`},
+		{name: "expired_cookie", req: request(http.MethodGet, "locked", nil, expired), handler: GetByIDHandler(db),
+			expectedCode: http.StatusOK, expectedBody: `name="password"`},
+		{name: "not_found", req: request(http.MethodPost, "missing", form("secret")), handler: UnlockHandler(db),
+			expectedCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, tt.req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.expectedCode)
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Body doesn't contain %q: %s", tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedCode == http.StatusOK && strings.Contains(w.Body.String(), u.FullURL) {
+				t.Errorf("Password page reveals original url")
+			}
+		})
+	}
+
+	t.Run("info_hides_url", func(t *testing.T) {
+		req := request(http.MethodGet, "locked", nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		InfoHandler(db)(w, req)
+
+		var info model.URLInfo
+		if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+			t.Fatalf("Failed to decode info: %v", err)
+		}
+		if !info.Protected || info.FullURL != "" {
+			t.Errorf("Info of protected link didn't match expected: %+v", info)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		body := `{"url":"https://docs.example/other","password":"` + strings.Repeat("p", maxPasswordLength+1) + `"}`
+		w := httptest.NewRecorder()
+		APIPostHandler(db)(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusBadRequest)
+		}
+
+		w = httptest.NewRecorder()
+		APIPostHandler(db)(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://docs.example/other","password":"pw"}`)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusCreated)
+		}
+
+		var resp model.APIPostResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		id := resp.Result[strings.LastIndex(resp.Result, "/")+1:]
+		created, _ := db.GetByID(context.Background(), id)
+		if created.PasswordHash == "" || created.PasswordHash == "pw" {
+			t.Errorf("Password is not stored as hash: %q", created.PasswordHash)
+		}
+	})
+	//Пароль на уже сокращенную открытую ссылку создает новую защищенную ссылку, а не возвращает открытую
+	t.Run("existing_url", func(t *testing.T) {
+		create := func(body string) (int, string) {
+			w := httptest.NewRecorder()
+			APIPostHandler(db)(w, httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body)))
+
+			var resp model.APIPostResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			return w.Code, resp.Result[strings.LastIndex(resp.Result, "/")+1:]
+		}
+
+		code, public := create(`{"url":"https://docs.example/handbook"}`)
+		if code != http.StatusCreated {
+			t.Fatalf("Response code didn't match expected: got %d want %d", code, http.StatusCreated)
+		}
+		code, protected := create(`{"url":"https://docs.example/handbook","password":"pw"}`)
+		if code != http.StatusCreated {
+			t.Fatalf("Response code didn't match expected: got %d want %d", code, http.StatusCreated)
+		}
+		if protected == public {
+			t.Fatalf("Protected link reuses public short url %s", public)
+		}
+
+		w := httptest.NewRecorder()
+		GetByIDHandler(db)(w, request(http.MethodGet, protected, nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `name="password"`) {
+			t.Errorf("New link is not protected: got %d", w.Code)
+		}
+
+		if code, again := create(`{"url":"https://docs.example/handbook"}`); code != http.StatusConflict || again != public {
+			t.Errorf("Repeated public link didn't match expected: got %d %s want %d %s", code, again, http.StatusConflict, public)
+		}
+	})
+}
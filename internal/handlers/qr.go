@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/qr"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler для получения QR-кода короткой ссылки. Параметры: format (png, svg), size, level (L, M, Q, H), margin
+func QRHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		short := chi.URLParam(req, "id")
+
+		if _, ok := db.GetByID(req.Context(), short); !ok {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+
+		opts, err := parseQROptions(req.URL.Query())
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		image, contentType, err := qr.Encode(shortURL(req.Context(), short), opts)
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res.Header().Set("Content-Type", contentType)
+		res.WriteHeader(http.StatusOK)
+		res.Write(image)
+	}
+}
+
+func parseQROptions(query url.Values) (qr.Options, error) {
+	opts := qr.DefaultOptions()
+
+	if format := query.Get("format"); format != "" {
+		opts.Format = format
+	}
+	if level := query.Get("level"); level != "" {
+		opts.Level = level
+	}
+	if size := query.Get("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return opts, qr.ErrInvalidSize
+		}
+		opts.Size = n
+	}
+	if margin := query.Get("margin"); margin != "" {
+		n, err := strconv.Atoi(margin)
+		if err != nil {
+			return opts, qr.ErrInvalidMargin
+		}
+		opts.Margin = n
+	}
+
+	return opts, nil
+}
+
+// Возвращает PNG QR-код ссылки в base64 для встраивания в ответ, пустую строку при ошибке
+func inlineQR(ctx context.Context, shortURL string) string {
+	image, _, err := qr.Encode(shortURL, qr.DefaultOptions())
+	if err != nil {
+		logger.FromContext(ctx).Debugw("request failed", "error", err)
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(image)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/IgorGreusunset/shortener/internal/qr"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+)
+
+func TestQRHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(model.URL{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/"}, true).AnyTimes()
+	m.EXPECT().GetByID(gomock.Any(), "yyokley").Return(model.URL{}, false)
+
+	tests := []struct {
+		name            string
+		requestID       string
+		query           string
+		expectedCode    int
+		expectedContent string
+		expectedSize    int
+	}{
+		{name: "default_png", requestID: "U8rtGB25", expectedCode: http.StatusOK, expectedContent: "image/png", expectedSize: qr.DefaultSize},
+		{name: "png_size", requestID: "U8rtGB25", query: "format=png&size=512&level=H&margin=0", expectedCode: http.StatusOK, expectedContent: "image/png", expectedSize: 512},
+		{name: "svg", requestID: "U8rtGB25", query: "format=svg&size=128", expectedCode: http.StatusOK, expectedContent: "image/svg+xml", expectedSize: 128},
+		{name: "invalid_format", requestID: "U8rtGB25", query: "format=gif", expectedCode: http.StatusBadRequest},
+		{name: "size_not_number", requestID: "U8rtGB25", query: "size=big", expectedCode: http.StatusBadRequest},
+		{name: "size_too_large", requestID: "U8rtGB25", query: fmt.Sprintf("size=%d", qr.MaxSize+1), expectedCode: http.StatusBadRequest},
+		{name: "size_zero", requestID: "U8rtGB25", query: "size=0", expectedCode: http.StatusBadRequest},
+		{name: "invalid_level", requestID: "U8rtGB25", query: "level=X", expectedCode: http.StatusBadRequest},
+		{name: "margin_not_number", requestID: "U8rtGB25", query: "margin=wide", expectedCode: http.StatusBadRequest},
+		{name: "negative_margin", requestID: "U8rtGB25", query: "margin=-1", expectedCode: http.StatusBadRequest},
+		{name: "margin_too_large", requestID: "U8rtGB25", query: fmt.Sprintf("margin=%d", qr.MaxMargin+1), expectedCode: http.StatusBadRequest},
+		{name: "not_found", requestID: "yyokley", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveQR(m, tt.requestID, tt.query)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.expectedCode)
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.expectedContent {
+				t.Errorf("Response content-type didn't match expected: got %v want %v", ct, tt.expectedContent)
+			}
+
+			switch tt.expectedContent {
+			case "image/png":
+				img, err := png.Decode(w.Body)
+				if err != nil {
+					t.Fatalf("Failed to decode png: %v", err)
+				}
+				if b := img.Bounds(); b.Dx() != tt.expectedSize || b.Dy() != tt.expectedSize {
+					t.Errorf("Image size didn't match expected: got %dx%d want %d", b.Dx(), b.Dy(), tt.expectedSize)
+				}
+			case "image/svg+xml":
+				body := w.Body.String()
+				size := fmt.Sprintf(`width="%d" height="%d"`, tt.expectedSize, tt.expectedSize)
+				if !strings.HasPrefix(body, "<svg") || !strings.Contains(body, size) {
+					t.Errorf("SVG didn't match expected size %d: %s", tt.expectedSize, body)
+				}
+			}
+		})
+	}
+
+	//Поле вокруг кода увеличивает число модулей на два margin
+	narrow := svgModules(t, serveQR(m, "U8rtGB25", "format=svg&margin=0"))
+	wide := svgModules(t, serveQR(m, "U8rtGB25", "format=svg&margin=5"))
+	if wide-narrow != 10 {
+		t.Errorf("Margin didn't match expected: got %d modules want %d", wide, narrow+10)
+	}
+}
+
+func TestAPIPostHandlerInlineQR(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.URLExistsError{ShortURL: "U8rtGB25"})
+
+	tests := []struct {
+		name         string
+		reqBody      model.APIPostRequest
+		expectedCode int
+		expectedQR   bool
+	}{
+		{name: "with_qr", reqBody: model.APIPostRequest{URL: "https://mail.ru/", QR: true}, expectedCode: http.StatusCreated, expectedQR: true},
+		{name: "without_qr", reqBody: model.APIPostRequest{URL: "https://mail.ru/"}, expectedCode: http.StatusCreated},
+		{name: "conflict_with_qr", reqBody: model.APIPostRequest{URL: "https://practicum.yandex.ru/", QR: true}, expectedCode: http.StatusConflict, expectedQR: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.reqBody)
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			APIPostHandler(m)(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.expectedCode)
+			}
+
+			var resp model.APIPostResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if !tt.expectedQR {
+				if resp.QR != "" || strings.Contains(w.Body.String(), `"qr"`) {
+					t.Errorf("Unexpected qr field in response: %s", w.Body.String())
+				}
+				return
+			}
+
+			data, err := base64.StdEncoding.DecodeString(resp.QR)
+			if err != nil {
+				t.Fatalf("Failed to decode qr field: %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Failed to decode inline png: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != qr.DefaultSize {
+				t.Errorf("Inline image size didn't match expected: got %d want %d", b.Dx(), qr.DefaultSize)
+			}
+
+			//QR-код совпадает с отдаваемым по /{id}/qr для той же ссылки
+			expected, _, err := qr.Encode(resp.Result, qr.DefaultOptions())
+			if err != nil {
+				t.Fatalf("Failed to encode expected qr: %v", err)
+			}
+			if !bytes.Equal(data, expected) {
+				t.Errorf("Inline qr didn't match code for %s", resp.Result)
+			}
+		})
+	}
+}
+
+// Выполняет запрос к QRHandler для короткой ссылки id
+func serveQR(db storage.Repository, id, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/"+id+"/qr?"+query, nil)
+
+	cntx := chi.NewRouteContext()
+	cntx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, cntx))
+
+	w := httptest.NewRecorder()
+	QRHandler(db)(w, req)
+	return w
+}
+
+// Число модулей по стороне SVG QR-кода
+func svgModules(t *testing.T, w *httptest.ResponseRecorder) int {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusOK)
+	}
+	var n int
+	body := w.Body.String()
+	i := strings.Index(body, `viewBox="0 0 `)
+	if i < 0 {
+		t.Fatalf("SVG viewBox not found: %s", body)
+	}
+	if _, err := fmt.Sscanf(body[i:], `viewBox="0 0 %d`, &n); err != nil {
+		t.Fatalf("Failed to parse SVG viewBox: %v", err)
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+)
+
+// Ошибка при исчерпании квоты ссылок. reset - время обновления квоты, нулевое, если квота со временем не обновляется
+type quotaError struct {
+	msg   string
+	reset time.Time
+}
+
+func (e *quotaError) Error() string {
+	return e.msg
+}
+
+var (
+	//Ошибка при исчерпании квоты ссылок тенанта
+	errTenantQuota = &quotaError{msg: "Link quota exceeded"}
+	//Ошибка при исчерпании квоты активных ссылок пользователя
+	errActiveQuota = &quotaError{msg: "Active link quota exceeded"}
+)
+
+// Проверяет, что ошибка вызвана исчерпанием квоты
+func isQuotaError(err error) bool {
+	var qe *quotaError
+	return errors.As(err, &qe)
+}
+
+// Создает ссылки с проверкой квот тенанта запроса и текущего пользователя. Квоты проверяет хранилище
+// вместе с созданием, поэтому параллельные запросы и удаление ссылок не позволяют превысить квоты
+func createWithQuota(ctx context.Context, db storage.Repository, urls []model.URL) error {
+	day := quotaDay(time.Now())
+	quota := model.Quota{MaxLinks: tenant.FromContext(ctx).MaxLinks, Day: day}
+	if userID, ok := auth.UserFromContext(ctx); ok {
+		quota.UserID = userID
+		quota.ActiveLimit = config.UserActiveLimit
+		quota.DailyLimit = config.UserDailyLimit
+	}
+
+	err := db.CreateWithQuota(ctx, urls, quota)
+	switch {
+	case errors.Is(err, storage.ErrTenantQuota):
+		return errTenantQuota
+	case errors.Is(err, storage.ErrActiveQuota):
+		return errActiveQuota
+	case errors.Is(err, storage.ErrDailyQuota):
+		return &quotaError{msg: "Daily link quota exceeded", reset: day.AddDate(0, 0, 1)}
+	}
+	return err
+}
+
+// Начало суток дневной квоты. Дневная квота обновляется в полночь по UTC
+func quotaDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Считает использование квот пользователем
+func userUsage(ctx context.Context, db storage.Repository, userID string, now time.Time) (model.UserUsage, error) {
+	day := quotaDay(now)
+
+	usage, err := db.Usage(ctx, userID, day)
+	if err != nil {
+		return model.UserUsage{}, err
+	}
+
+	return model.UserUsage{
+		Active:      usage.Active,
+		ActiveLimit: config.UserActiveLimit,
+		Daily:       usage.Created,
+		DailyLimit:  config.UserDailyLimit,
+		Reset:       day.AddDate(0, 0, 1),
+	}, nil
+}
+
+// Записывает ответ для ошибки проверки квоты. Для обновляемой квоты сообщает время обновления в Retry-After
+func writeQuotaError(res http.ResponseWriter, req *http.Request, err error) {
+	var qe *quotaError
+	if !errors.As(err, &qe) {
+		logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		writeAPIError(res, req, http.StatusInternalServerError, "Failed to check quota")
+		return
+	}
+
+	if qe.reset.IsZero() {
+		writeAPIError(res, req, http.StatusTooManyRequests, qe.msg)
+		return
+	}
+	//Округляем вверх, чтобы клиент не повторил запрос до обновления квоты
+	wait := time.Until(qe.reset)
+	res.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	writeAPIError(res, req, http.StatusTooManyRequests, fmt.Sprintf("%s, resets at %s", qe.msg, qe.reset.Format(time.RFC3339)))
+}
+
+// Короткая ссылка на домене тенанта запроса
+func shortURL(ctx context.Context, id string) string {
+	return tenant.FromContext(ctx).ShortURL(id)
+}
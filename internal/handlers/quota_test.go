@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+)
+
+func TestUserQuota(t *testing.T) {
+	defer func(daily, active int) {
+		config.UserDailyLimit, config.UserActiveLimit = daily, active
+	}(config.UserDailyLimit, config.UserActiveLimit)
+	config.UserDailyLimit, config.UserActiveLimit = 2, 3
+
+	//Отключенные ссылки не входят в квоту активных. Ссылки, созданные до запуска хранилища, не входят в дневную квоту
+	db := storage.NewStorage(map[string]model.URL{
+		"old":      {ID: "old", FullURL: "https://old.example/", UserID: "user", Created: time.Now().AddDate(0, 0, -2)},
+		"disabled": {ID: "disabled", FullURL: "https://disabled.example/", UserID: "user", Created: time.Now().AddDate(0, 0, -2), Disabled: true},
+		"other":    {ID: "other", FullURL: "https://other.example/", UserID: "other", Created: time.Now(), Disabled: true},
+	})
+
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		body         string
+		user         string
+		expectedCode int
+		retryAfter   bool
+	}{
+		{name: "batch_over_active", handler: BathcHandler(db), user: "user", expectedCode: http.StatusTooManyRequests,
+			body: `[{"correlation_id":"1","original_url":"https://a.example"},{"correlation_id":"2","original_url":"https://b.example"},{"correlation_id":"3","original_url":"https://c.example"}]`},
+		{name: "batch", handler: BathcHandler(db), user: "user", expectedCode: http.StatusCreated,
+			body: `[{"correlation_id":"1","original_url":"https://a.example"},{"correlation_id":"2","original_url":"https://b.example"}]`},
+		{name: "active_exhausted", handler: APIPostHandler(db), user: "user", expectedCode: http.StatusTooManyRequests,
+			body: `{"url":"https://c.example"}`},
+		{name: "other_user", handler: PostHandler(db), user: "other", expectedCode: http.StatusCreated,
+			body: `https://d.example`},
+		{name: "batch_over_daily", handler: BathcHandler(db), user: "other", expectedCode: http.StatusTooManyRequests, retryAfter: true,
+			body: `[{"correlation_id":"1","original_url":"https://e.example"},{"correlation_id":"2","original_url":"https://f.example"}]`},
+		{name: "other_user_last", handler: APIPostHandler(db), user: "other", expectedCode: http.StatusCreated,
+			body: `{"url":"https://e.example"}`},
+		{name: "daily_exhausted", handler: PostHandler(db), user: "other", expectedCode: http.StatusTooManyRequests, retryAfter: true,
+			body: `https://f.example`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req = req.WithContext(auth.WithUser(req.Context(), tt.user))
+
+			w := httptest.NewRecorder()
+			middleware.WithRequestID(tt.handler).ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.expectedCode)
+			}
+			if got := w.Header().Get("Retry-After") != ""; got != tt.retryAfter {
+				t.Errorf("Retry-After presence didn't match expected: got %v want %v", got, tt.retryAfter)
+			}
+			if tt.expectedCode != http.StatusTooManyRequests {
+				return
+			}
+
+			//Тело ошибки содержит идентификатор запроса из заголовка
+			var apiErr model.APIError
+			if err := json.NewDecoder(w.Body).Decode(&apiErr); err != nil {
+				t.Fatalf("Failed to decode error: %v", err)
+			}
+			if apiErr.Error == "" || apiErr.RequestID == "" || apiErr.RequestID != w.Header().Get(middleware.RequestIDHeader) {
+				t.Errorf("Error body didn't match expected: got %+v, request id header %q", apiErr, w.Header().Get(middleware.RequestIDHeader))
+			}
+		})
+	}
+
+	t.Run("usage", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/usage", nil)
+		req = req.WithContext(auth.WithUser(req.Context(), "user"))
+
+		w := httptest.NewRecorder()
+		UsageHandler(db)(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusOK)
+		}
+
+		var usage model.UserUsage
+		if err := json.NewDecoder(w.Body).Decode(&usage); err != nil {
+			t.Fatalf("Failed to decode usage: %v", err)
+		}
+		expected := model.UserUsage{Active: 3, ActiveLimit: 3, Daily: 2, DailyLimit: 2, Reset: usage.Reset}
+		if usage != expected {
+			t.Errorf("Usage didn't match expected: got %+v want %+v", usage, expected)
+		}
+		if !usage.Reset.After(time.Now()) || usage.Reset.Sub(time.Now()) > 24*time.Hour {
+			t.Errorf("Reset time is not within next day: %v", usage.Reset)
+		}
+	})
+	//Удаленная ссылка не возвращается в дневную квоту
+	t.Run("delete_keeps_daily_usage", func(t *testing.T) {
+		post := func(body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req = req.WithContext(auth.WithUser(req.Context(), "deleter"))
+			w := httptest.NewRecorder()
+			PostHandler(db)(w, req)
+			return w
+		}
+
+		for _, body := range []string{"https://g.example", "https://h.example"} {
+			w := post(body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, http.StatusCreated)
+			}
+			short := w.Body.String()
+			if err := db.Delete(context.Background(), short[strings.LastIndex(short, "/")+1:]); err != nil {
+				t.Fatalf("Failed to delete url: %v", err)
+			}
+		}
+
+		if w := post("https://i.example"); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Response code didn't match expected: got %d want %d", w.Code, http.StatusTooManyRequests)
+		}
+	})
+
+	//Параллельные запросы пользователя не превышают дневную квоту
+	t.Run("concurrent", func(t *testing.T) {
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(fmt.Sprintf("https://burst.example/%d", i)))
+				req = req.WithContext(auth.WithUser(req.Context(), "burst"))
+				w := httptest.NewRecorder()
+				PostHandler(db)(w, req)
+				if w.Code == http.StatusCreated {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if created != config.UserDailyLimit {
+			t.Errorf("Created links didn't match daily limit: got %d want %d", created, config.UserDailyLimit)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTenants(t *testing.T) {
+	alpha := tenant.Tenant{ID: "alpha", BaseURL: "https://a.example", MaxLinks: 2}
+	beta := tenant.Tenant{ID: "beta", BaseURL: "https://b.example"}
+
+	//Один и тот же короткий ID ведет на разные ссылки в разных тенантах
+	db := storage.NewStorage(map[string]model.URL{})
+	db.Create(tenant.WithTenant(context.Background(), alpha), model.NewURL("same", "https://alpha.example/"))
+	db.Create(tenant.WithTenant(context.Background(), beta), model.NewURL("same", "https://beta.example/"))
+
+	t.Run("redirect", func(t *testing.T) {
+		for _, tc := range []struct {
+			tenant   tenant.Tenant
+			expected string
+		}{{alpha, "https://alpha.example/"}, {beta, "https://beta.example/"}} {
+			tn, expected := tc.tenant, tc.expected
+			req := httptest.NewRequest(http.MethodGet, "/same", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "same")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(tenant.WithTenant(ctx, tn))
+
+			w := httptest.NewRecorder()
+			GetByIDHandler(db)(w, req)
+
+			if got := w.Header().Get("Location"); got != expected {
+				t.Errorf("Location for tenant %s didn't match expected: got %s want %s", tn.ID, got, expected)
+			}
+		}
+
+		//В тенанте по умолчанию такой ссылки нет
+		req := httptest.NewRequest(http.MethodGet, "/same", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "same")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		GetByIDHandler(db)(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Response code didn't match expected: got %d want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		tests := []struct {
+			body         string
+			expectedCode int
+		}{
+			{body: `{"url":"https://one.example"}`, expectedCode: http.StatusCreated},
+			{body: `{"url":"https://two.example"}`, expectedCode: http.StatusTooManyRequests},
+		}
+
+		for _, tt := range tests {
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(tt.body))
+			req = req.WithContext(tenant.WithTenant(req.Context(), alpha))
+
+			w := httptest.NewRecorder()
+			APIPostHandler(db)(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.expectedCode)
+			}
+			if w.Code == http.StatusCreated && !strings.Contains(w.Body.String(), `"https://a.example/`) {
+				t.Errorf("Short url is not on tenant domain: %s", w.Body.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var uiTemplate = template.Must(template.ParseFS(templatesFS, "templates/ui.html"))
+
+// Ссылка в таблице "Мои ссылки"
+type uiLink struct {
+	ID string
+	*model.URLInfo
+}
+
+// Данные страницы веб-интерфейса
+type uiPage struct {
+	CSRF    string
+	URL     string
+	Created string
+	Error   string
+	Links   []uiLink
+}
+
+// Handler для страницы веб-интерфейса: форма сокращения и ссылки текущего пользователя
+func UIHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		page := uiPage{}
+		if id := req.URL.Query().Get("created"); id != "" {
+			page.Created = shortURL(req.Context(), id)
+		}
+		renderUI(res, req, db, http.StatusOK, page)
+	}
+}
+
+// Handler для формы сокращения ссылки. После успешного создания перенаправляет на страницу интерфейса,
+// чтобы обновление страницы не отправляло форму повторно
+func UIShortenHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		limitBody(res, req)
+		if err := req.ParseForm(); err != nil {
+			writeBodyError(res, err)
+			return
+		}
+		if !validCSRF(req) {
+			http.Error(res, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		rawURL := req.PostForm.Get("url")
+		fullURL, err := normalizeURL(rawURL)
+		if err != nil {
+			renderUI(res, req, db, urlErrorStatus(err), uiPage{URL: rawURL, Error: err.Error()})
+			return
+		}
+
+		passwordHash, err := hashPassword(req.PostForm.Get("password"))
+		if err != nil {
+			if errors.Is(err, errPasswordTooLong) {
+				renderUI(res, req, db, http.StatusBadRequest, uiPage{URL: rawURL, Error: "Пароль длиннее 72 байт"})
+				return
+			}
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			renderUI(res, req, db, http.StatusInternalServerError, uiPage{URL: rawURL, Error: "Не удалось сохранить ссылку"})
+			return
+		}
+
+		urlToAdd := model.NewURL("", fullURL)
+		urlToAdd.PasswordHash = passwordHash
+		id, err := shorten(req.Context(), db, urlToAdd)
+		if err != nil {
+			var uee *storage.URLExistsError
+			if isQuotaError(err) {
+				renderUI(res, req, db, http.StatusTooManyRequests, uiPage{URL: rawURL, Error: "Превышена квота ссылок"})
+				return
+			}
+			if !errors.As(err, &uee) {
+				logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+				renderUI(res, req, db, http.StatusInternalServerError, uiPage{URL: rawURL, Error: "Не удалось сохранить ссылку"})
+				return
+			}
+			//Защищенная паролем ссылка всегда создается новой, поэтому повтором может быть только открытая ссылка
+			id = uee.ShortURL
+		}
+
+		http.Redirect(res, req, "/ui?created="+url.QueryEscape(id), http.StatusSeeOther)
+	}
+}
+
+// Handler для удаления ссылки из веб-интерфейса
+func UIDeleteHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		limitBody(res, req)
+		if err := req.ParseForm(); err != nil {
+			writeBodyError(res, err)
+			return
+		}
+		if !validCSRF(req) {
+			http.Error(res, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		u, ok := ownedURL(res, req, db)
+		if !ok {
+			return
+		}
+
+		userID, _ := auth.UserFromContext(req.Context())
+		if err := deleteUserURLs(req.Context(), db, userID, []string{u.ID}); err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to delete url", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(res, req, "/ui", http.StatusSeeOther)
+	}
+}
+
+// Дополняет страницу ссылками пользователя и токеном формы и выводит ее
+func renderUI(res http.ResponseWriter, req *http.Request, db storage.Repository, status int, page uiPage) {
+	userID, _ := auth.UserFromContext(req.Context())
+	page.CSRF = csrfToken(userID)
+
+	urls, err := db.ListByUser(req.Context(), userID)
+	if err != nil {
+		logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+		page.Error = "Не удалось загрузить ссылки"
+	}
+	for _, u := range urls {
+		page.Links = append(page.Links, uiLink{ID: u.ID, URLInfo: model.NewURLInfo(shortURL(req.Context(), u.ID), u)})
+	}
+
+	res.Header().Set("Content-Type", "text/html")
+	res.WriteHeader(status)
+	if err := uiTemplate.Execute(res, page); err != nil {
+		logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+	}
+}
+
+// Токен формы, привязанный к пользователю из cookie. Сторонний сайт не может его узнать,
+// поэтому не может отправить форму от имени пользователя
+func csrfToken(userID string) string {
+	return auth.Token("csrf", userID, []byte(config.SecretKey))
+}
+
+func validCSRF(req *http.Request) bool {
+	userID, ok := auth.UserFromContext(req.Context())
+	if !ok {
+		return false
+	}
+	token := req.PostForm.Get("csrf_token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(csrfToken(userID))) == 1
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+)
+
+func TestUIHandlers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().ListByUser(gomock.Any(), "owner").Return([]model.URL{
+		{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", UserID: "owner", Clicks: 7},
+	}, nil).AnyTimes()
+	m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(model.URL{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", UserID: "owner"}, true).AnyTimes()
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().CreateWithQuota(gomock.Any(), gomock.Any(), gomock.Any()).Return(&storage.URLExistsError{ShortURL: "g7RETf01"})
+	m.EXPECT().Delete(gomock.Any(), "U8rtGB25").Return(nil)
+
+	token := csrfToken("owner")
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		id               string
+		form             url.Values
+		handler          http.HandlerFunc
+		expectedCode     int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{
+			name:         "page",
+			method:       http.MethodGet,
+			path:         "/ui",
+			handler:      UIHandler(m),
+			expectedCode: http.StatusOK,
+			expectedBody: "https://practicum.yandex.ru/",
+		},
+		{
+			name:             "shorten",
+			method:           http.MethodPost,
+			path:             "/ui/shorten",
+			form:             url.Values{"url": {"https://mail.ru/"}, "csrf_token": {token}},
+			handler:          UIShortenHandler(m),
+			expectedCode:     http.StatusSeeOther,
+			expectedLocation: "/ui?created=",
+		},
+		{
+			name:             "shorten_exists",
+			method:           http.MethodPost,
+			path:             "/ui/shorten",
+			form:             url.Values{"url": {"https://ya.ru/"}, "csrf_token": {token}},
+			handler:          UIShortenHandler(m),
+			expectedCode:     http.StatusSeeOther,
+			expectedLocation: "/ui?created=g7RETf01",
+		},
+		{
+			name:         "shorten_not_url",
+			method:       http.MethodPost,
+			path:         "/ui/shorten",
+			form:         url.Values{"url": {"not url"}, "csrf_token": {token}},
+			handler:      UIShortenHandler(m),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `class="message error"`,
+		},
+		{
+			name:         "shorten_bad_csrf",
+			method:       http.MethodPost,
+			path:         "/ui/shorten",
+			form:         url.Values{"url": {"https://mail.ru/"}, "csrf_token": {"forged"}},
+			handler:      UIShortenHandler(m),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:             "delete",
+			method:           http.MethodPost,
+			path:             "/ui/links/U8rtGB25/delete",
+			id:               "U8rtGB25",
+			form:             url.Values{"csrf_token": {token}},
+			handler:          UIDeleteHandler(m),
+			expectedCode:     http.StatusSeeOther,
+			expectedLocation: "/ui",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			cntx := chi.NewRouteContext()
+			cntx.URLParams.Add("id", tt.id)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, cntx)
+			req = req.WithContext(auth.WithUser(ctx, "owner"))
+
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+			if !strings.HasPrefix(res.Header.Get("Location"), tt.expectedLocation) {
+				t.Errorf("Response Location didn't match expected: got %v want %v", res.Header.Get("Location"), tt.expectedLocation)
+			}
+
+			body, _ := io.ReadAll(res.Body)
+			if !strings.Contains(string(body), tt.expectedBody) {
+				t.Errorf("Response body doesn't contain %q", tt.expectedBody)
+			}
+		})
+	}
+}
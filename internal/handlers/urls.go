@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler для смены полной ссылки владельцем
+func UpdateURLHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		u, ok := ownedURL(res, req, db)
+		if !ok {
+			return
+		}
+
+		var body model.UpdateURLRequest
+		if err := json.NewDecoder(limitBody(res, req)).Decode(&body); err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyError(res, err)
+				return
+			}
+			http.Error(res, "Failed decoding request body", http.StatusBadRequest)
+			return
+		}
+
+		fullURL, err := normalizeURL(body.URL)
+		if err != nil {
+			writeURLError(res, err)
+			return
+		}
+
+		updated, ok := retarget(res, req, db, u.ID, fullURL, u.UserID)
+		if !ok {
+			return
+		}
+
+		//Владельцу отдаем сведения о ссылке без служебных полей записи
+		writeJSON(res, req, http.StatusOK, model.NewURLInfo(shortURL(req.Context(), updated.ID), updated))
+	}
+}
+
+// Handler для получения истории изменений полной ссылки владельцем
+func HistoryHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		u, ok := ownedURL(res, req, db)
+		if !ok {
+			return
+		}
+
+		history, err := db.History(req.Context(), u.ID)
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to get history", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(res, req, http.StatusOK, history)
+	}
+}
+
+// Handler для получения ссылок текущего пользователя. Если ссылок нет, отвечает 204
+func UserURLsHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		urls, err := db.ListByUser(req.Context(), userID)
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to list urls", http.StatusInternalServerError)
+			return
+		}
+		if len(urls) == 0 {
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		links := make([]*model.URLInfo, 0, len(urls))
+		for _, u := range urls {
+			links = append(links, model.NewURLInfo(shortURL(req.Context(), u.ID), u))
+		}
+
+		writeJSON(res, req, http.StatusOK, links)
+	}
+}
+
+// Handler для получения использования квот текущим пользователем
+func UsageHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		usage, err := userUsage(req.Context(), db, userID, time.Now())
+		if err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to count usage", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(res, req, http.StatusOK, usage)
+	}
+}
+
+// Handler для удаления ссылок текущего пользователя по списку ID. Чужие и несуществующие ID пропускаются
+func DeleteUserURLsHandler(db storage.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, ok := auth.UserFromContext(req.Context())
+		if !ok {
+			http.Error(res, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var ids []string
+		if err := json.NewDecoder(limitBody(res, req)).Decode(&ids); err != nil {
+			if isBodyTooLarge(err) {
+				writeBodyError(res, err)
+				return
+			}
+			http.Error(res, "Failed decoding request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := deleteUserURLs(req.Context(), db, userID, ids); err != nil {
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to delete urls", http.StatusInternalServerError)
+			return
+		}
+
+		res.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// Удаляет ссылки из ids, принадлежащие пользователю
+func deleteUserURLs(ctx context.Context, db storage.Repository, userID string, ids []string) error {
+	for _, id := range ids {
+		u, ok := db.GetByID(ctx, id)
+		if !ok || u.UserID != userID {
+			continue
+		}
+		if err := db.Delete(ctx, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Находит ссылку по ID из пути и проверяет, что она принадлежит текущему пользователю.
+// При неудаче сам записывает ответ
+func ownedURL(res http.ResponseWriter, req *http.Request, db storage.Repository) (model.URL, bool) {
+	userID, ok := auth.UserFromContext(req.Context())
+	if !ok {
+		http.Error(res, "Unauthorized", http.StatusUnauthorized)
+		return model.URL{}, false
+	}
+
+	u, ok := db.GetByID(req.Context(), chi.URLParam(req, "id"))
+	if !ok {
+		http.Error(res, "Not found", http.StatusNotFound)
+		return model.URL{}, false
+	}
+
+	if u.UserID != userID {
+		http.Error(res, "Forbidden", http.StatusForbidden)
+		return model.URL{}, false
+	}
+
+	return u, true
+}
+
+// Меняет полную ссылку от имени actor и возвращает обновленную запись. При ошибке сам записывает ответ
+func retarget(res http.ResponseWriter, req *http.Request, db storage.Repository, id, newURL, actor string) (model.URL, bool) {
+	u, err := db.Retarget(req.Context(), id, newURL, actor)
+	if err != nil {
+		var uee *storage.URLExistsError
+		switch {
+		case errors.As(err, &uee):
+			http.Error(res, "Original URL already stored as "+uee.ShortURL, http.StatusConflict)
+		case errors.Is(err, storage.ErrNotFound):
+			http.Error(res, "Not found", http.StatusNotFound)
+		default:
+			logger.FromContext(req.Context()).Debugw("request failed", "error", err)
+			http.Error(res, "Failed to update url", http.StatusInternalServerError)
+		}
+		return model.URL{}, false
+	}
+	return u, true
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/mocks"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+)
+
+func TestUpdateURLHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(model.URL{ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", UserID: "owner"}, true).AnyTimes()
+	m.EXPECT().Retarget(gomock.Any(), "U8rtGB25", "https://mail.ru/", "owner").Return(model.URL{ID: "U8rtGB25", FullURL: "https://mail.ru/", UserID: "owner", PasswordHash: "$2a$10$hash"}, nil)
+	m.EXPECT().Retarget(gomock.Any(), "U8rtGB25", "https://ya.ru/", "owner").Return(model.URL{}, &storage.URLExistsError{ShortURL: "g7RETf01"})
+
+	tests := []struct {
+		name         string
+		userID       string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "owner",
+			userID:       "owner",
+			body:         `{"original_url":"https://mail.ru/"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not_owner",
+			userID:       "stranger",
+			body:         `{"original_url":"https://mail.ru/"}`,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "url_exists",
+			userID:       "owner",
+			body:         `{"original_url":"https://ya.ru/"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "not_url",
+			userID:       "owner",
+			body:         `{"original_url":"not url"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/urls/U8rtGB25", strings.NewReader(tt.body))
+
+			cntx := chi.NewRouteContext()
+			cntx.URLParams.Add("id", "U8rtGB25")
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, cntx)
+			req = req.WithContext(auth.WithUser(ctx, tt.userID))
+
+			w := httptest.NewRecorder()
+			UpdateURLHandler(m)(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+			//Ответ не раскрывает хеш пароля и владельца
+			if body := w.Body.String(); strings.Contains(body, "password_hash") || strings.Contains(body, "user_id") {
+				t.Errorf("Response exposes record fields: %s", body)
+			}
+		})
+	}
+}
+
+func TestUserURLsHandlers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mocks.NewMockRepository(ctrl)
+	m.EXPECT().ListByUser(gomock.Any(), "owner").Return([]model.URL{{ID: "U8rtGB25", FullURL: "https://mail.ru/", UserID: "owner"}}, nil)
+	m.EXPECT().ListByUser(gomock.Any(), "stranger").Return(nil, nil)
+	m.EXPECT().GetByID(gomock.Any(), "U8rtGB25").Return(model.URL{ID: "U8rtGB25", UserID: "owner"}, true).AnyTimes()
+	m.EXPECT().GetByID(gomock.Any(), "yyokley").Return(model.URL{}, false).AnyTimes()
+	//Удаляется только ссылка владельца
+	m.EXPECT().Delete(gomock.Any(), "U8rtGB25").Return(nil).Times(1)
+
+	tests := []struct {
+		name         string
+		method       string
+		userID       string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "list",
+			method:       http.MethodGet,
+			userID:       "owner",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "list_empty",
+			method:       http.MethodGet,
+			userID:       "stranger",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "delete_not_owner",
+			method:       http.MethodDelete,
+			userID:       "stranger",
+			body:         `["U8rtGB25"]`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "delete",
+			method:       http.MethodDelete,
+			userID:       "owner",
+			body:         `["U8rtGB25","yyokley"]`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "delete_bad_body",
+			method:       http.MethodDelete,
+			userID:       "owner",
+			body:         `{"id":"U8rtGB25"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/user/urls", strings.NewReader(tt.body))
+			req = req.WithContext(auth.WithUser(req.Context(), tt.userID))
+
+			w := httptest.NewRecorder()
+			if tt.method == http.MethodGet {
+				UserURLsHandler(m)(w, req)
+			} else {
+				DeleteUserURLsHandler(m)(w, req)
+			}
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+		})
+	}
+}
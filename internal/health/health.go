@@ -0,0 +1,170 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Проверка одного компонента сервиса, nil означает исправность
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Адаптер функции к интерфейсу Checker
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Состояние компонента в ответе /readyz
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Ответ проверок готовности
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Реестр проверок готовности. Компоненты регистрируют проверки при запуске,
+// при остановке сервиса готовность снимается независимо от результатов проверок
+type Registry struct {
+	mu       sync.RWMutex
+	checks   map[string]Checker
+	timeout  time.Duration
+	shutdown atomic.Bool
+}
+
+// Фабричный метод реестра, timeout ограничивает время каждой проверки
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{checks: map[string]Checker{}, timeout: timeout}
+}
+
+// Добавляет проверку компонента name, повторная регистрация заменяет проверку
+func (r *Registry) Register(name string, c Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = c
+}
+
+// Снимает готовность, чтобы балансировщик перестал направлять запросы до остановки сервера
+func (r *Registry) SetShuttingDown() {
+	r.shutdown.Store(true)
+}
+
+// Запускает фоновую задачу fn и регистрирует проверку worker:name, которая не проходит,
+// если задача завершилась раньше остановки сервиса или упала с паникой
+func (r *Registry) Go(ctx context.Context, name string, fn func(ctx context.Context)) {
+	var stopped atomic.Value
+	r.Register("worker:"+name, CheckFunc(func(context.Context) error {
+		if reason, ok := stopped.Load().(string); ok {
+			return fmt.Errorf("worker stopped: %s", reason)
+		}
+		return nil
+	}))
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				stopped.Store(fmt.Sprintf("panic: %v", p))
+				return
+			}
+			//Штатное завершение по отмене контекста сбоем не считается
+			if ctx.Err() == nil {
+				stopped.Store("exited")
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// Выполняет все проверки параллельно и собирает отчет
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	checks := make([]Checker, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+			results[i] = c.Check(cctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus, len(names))}
+	for i, name := range names {
+		if results[i] != nil {
+			report.Status = StatusFail
+			report.Components[name] = ComponentStatus{Status: StatusFail, Error: results[i].Error()}
+			continue
+		}
+		report.Components[name] = ComponentStatus{Status: StatusOK}
+	}
+	if r.shutdown.Load() {
+		report.Status = StatusFail
+		report.Components["shutdown"] = ComponentStatus{Status: StatusFail, Error: "server is shutting down"}
+	}
+
+	return report
+}
+
+// Handler для проверки готовности: 200, если все компоненты исправны, иначе 503
+func (r *Registry) ReadinessHandler() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context())
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(res, status, report)
+	}
+}
+
+// Handler для проверки живости: процесс отвечает на запросы, зависимости не проверяются,
+// чтобы недоступность БД не приводила к перезапуску сервиса
+func LivenessHandler() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		writeReport(res, http.StatusOK, Report{Status: StatusOK})
+	}
+}
+
+func writeReport(res http.ResponseWriter, status int, report Report) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(status)
+	res.Write(body)
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	ok := CheckFunc(func(context.Context) error { return nil })
+	broken := CheckFunc(func(context.Context) error { return errors.New("connection refused") })
+	slow := CheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	tests := []struct {
+		name         string
+		checks       map[string]Checker
+		shutdown     bool
+		expectedCode int
+		failed       []string
+	}{
+		{
+			name:         "all_ok",
+			checks:       map[string]Checker{"database": ok, "file": ok},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "database_down",
+			checks:       map[string]Checker{"database": broken, "file": ok},
+			expectedCode: http.StatusServiceUnavailable,
+			failed:       []string{"database"},
+		},
+		{
+			name:         "timeout",
+			checks:       map[string]Checker{"cache": slow},
+			expectedCode: http.StatusServiceUnavailable,
+			failed:       []string{"cache"},
+		},
+		{
+			name:         "shutting_down",
+			checks:       map[string]Checker{"database": ok},
+			shutdown:     true,
+			expectedCode: http.StatusServiceUnavailable,
+			failed:       []string{"shutdown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(10 * time.Millisecond)
+			for name, c := range tt.checks {
+				r.Register(name, c)
+			}
+			if tt.shutdown {
+				r.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			r.ReadinessHandler()(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			res := w.Result()
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", res.StatusCode, tt.expectedCode)
+			}
+
+			var report Report
+			if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.failed {
+				if report.Components[name].Status != StatusFail {
+					t.Errorf("Component %s status didn't match expected: got %q want %q", name, report.Components[name].Status, StatusFail)
+				}
+			}
+		})
+	}
+}
+
+func TestGo(t *testing.T) {
+	r := NewRegistry(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	r.Go(ctx, "running", func(ctx context.Context) { <-ctx.Done() })
+	r.Go(ctx, "crashed", func(context.Context) {
+		defer close(done)
+		panic("boom")
+	})
+	<-done
+	//Даем горутине записать причину остановки после паники
+	time.Sleep(10 * time.Millisecond)
+
+	report := r.Run(context.Background())
+	if report.Components["worker:running"].Status != StatusOK {
+		t.Errorf("Running worker status didn't match expected: got %q", report.Components["worker:running"].Status)
+	}
+	if report.Components["worker:crashed"].Status != StatusFail {
+		t.Errorf("Crashed worker status didn't match expected: got %q", report.Components["worker:crashed"].Status)
+	}
+}
@@ -3,6 +3,7 @@ package helpers
 import (
 	"strings"
 	"math/rand"
+	"net/http"
 )
 
 var chars = []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
@@ -17,4 +18,13 @@ func Generate() string {
 		b.WriteRune(chars[rand.Intn(len(chars))])
 	}
 	return b.String()
-}
\ No newline at end of file
+}
+
+//Проверяет, что код подходит для ответа-перенаправления на полную ссылку
+func IsRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -1,18 +1,53 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"context"
+	"fmt"
 
-var Log zap.SugaredLogger
+	"go.uber.org/zap"
+)
 
-func Initialize() error {
+// До инициализации логгер ничего не пишет, чтобы пакеты можно было использовать в тестах
+var Log = *zap.NewNop().Sugar()
 
-	logger, err := zap.NewDevelopment()
+type ctxKey struct{}
+
+// Настраивает глобальный логгер. level - debug, info, warn или error; encoding - json или console
+func Initialize(level, encoding string) error {
+	lvl, err := zap.ParseAtomicLevel(level)
 	if err != nil {
 		return err
 	}
 
+	var cfg zap.Config
+	switch encoding {
+	case "json":
+		cfg = zap.NewProductionConfig()
+	case "console":
+		cfg = zap.NewDevelopmentConfig()
+	default:
+		return fmt.Errorf("unknown log encoding %q: want json or console", encoding)
+	}
+	cfg.Level = lvl
+
+	logger, err := cfg.Build()
+	if err != nil {
+		return err
+	}
 
 	Log = *logger.Sugar()
 	return nil
 }
 
+// Сохраняет в контексте логгер с полями запроса
+func WithContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// Логгер запроса из контекста, глобальный логгер, если в контексте его нет
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return &Log
+}
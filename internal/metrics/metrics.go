@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Реестр метрик сервиса
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shortener_http_requests_total",
+		Help: "Number of HTTP requests by chi route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shortener_http_request_duration_seconds",
+		Help:    "HTTP request latency by chi route pattern and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	Redirects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shortener_redirects_total",
+		Help: "Number of redirect lookups by result: hit or miss.",
+	}, []string{"result"})
+
+	StorageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "shortener_storage_operation_duration_seconds",
+		Help:    "Storage operation latency by backend and method.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"backend", "method"})
+
+	StorageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "shortener_storage_operation_errors_total",
+		Help: "Number of failed storage operations by backend and method.",
+	}, []string{"backend", "method"})
+
+	BatchSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "shortener_batch_size",
+		Help:    "Number of urls in batch requests.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+	})
+
+	GzipRatio = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "shortener_gzip_compression_ratio",
+		Help:    "Ratio of uncompressed to compressed response size.",
+		Buckets: []float64{1, 1.5, 2, 3, 4, 6, 8, 12, 16},
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		Redirects,
+		StorageDuration,
+		StorageErrors,
+		BatchSize,
+		GzipRatio,
+	)
+}
+
+// Учитывает результат поиска ссылки для перенаправления
+func ObserveRedirect(found bool) {
+	if found {
+		Redirects.WithLabelValues("hit").Inc()
+	} else {
+		Redirects.WithLabelValues("miss").Inc()
+	}
+}
+
+// Регистрирует метрики пула соединений с БД
+func RegisterDB(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, "shortener"))
+}
+
+// Handler для отдачи метрик в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRepository(t *testing.T) {
+	repo := NewRepository(storage.NewStorage(map[string]model.URL{}), "memory")
+	ctx := context.Background()
+
+	if err := repo.Create(ctx, model.NewURL("U8rtGB25", "https://mail.ru/")); err != nil {
+		t.Fatal(err)
+	}
+	repo.GetByID(ctx, "U8rtGB25")
+	if err := repo.Delete(ctx, "yyokley"); err == nil {
+		t.Fatalf("Expected error for missing url")
+	}
+
+	//По одному ряду гистограммы на каждый вызванный метод
+	if n := testutil.CollectAndCount(StorageDuration, "shortener_storage_operation_duration_seconds"); n != 3 {
+		t.Errorf("Duration series count didn't match expected: got %d want 3", n)
+	}
+
+	if got := testutil.ToFloat64(StorageErrors.WithLabelValues("memory", "Delete")); got != 1 {
+		t.Errorf("Delete errors didn't match expected: got %v want 1", got)
+	}
+	if got := testutil.ToFloat64(StorageErrors.WithLabelValues("memory", "Create")); got != 0 {
+		t.Errorf("Create errors didn't match expected: got %v want 0", got)
+	}
+}
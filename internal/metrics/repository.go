@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+)
+
+// Декоратор хранилища, замеряющий длительность и ошибки каждой операции
+type Repository struct {
+	next    storage.Repository
+	backend string
+}
+
+var _ storage.Repository = (*Repository)(nil)
+
+// Оборачивает хранилище, backend используется как метка метрик (file, postgres)
+func NewRepository(next storage.Repository, backend string) *Repository {
+	return &Repository{next: next, backend: backend}
+}
+
+// Фиксирует длительность операции method, начатой в start, и ошибку, если она есть.
+// Ошибка передается указателем, чтобы в defer прочитать итоговое значение
+func (r *Repository) observe(method string, start time.Time, err *error) {
+	StorageDuration.WithLabelValues(r.backend, method).Observe(time.Since(start).Seconds())
+	if err != nil && *err != nil {
+		StorageErrors.WithLabelValues(r.backend, method).Inc()
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, record *model.URL) (err error) {
+	defer r.observe("Create", time.Now(), &err)
+	return r.next.Create(ctx, record)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (model.URL, bool) {
+	defer r.observe("GetByID", time.Now(), nil)
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *Repository) Ping() (err error) {
+	defer r.observe("Ping", time.Now(), &err)
+	return r.next.Ping()
+}
+
+func (r *Repository) CreateBatch(ctx context.Context, urls []model.URL) (err error) {
+	defer r.observe("CreateBatch", time.Now(), &err)
+	return r.next.CreateBatch(ctx, urls)
+}
+
+func (r *Repository) Walk(ctx context.Context, fn func(model.URL) error) (err error) {
+	defer r.observe("Walk", time.Now(), &err)
+	return r.next.Walk(ctx, fn)
+}
+
+func (r *Repository) List(ctx context.Context, query string) (urls []model.URL, err error) {
+	defer r.observe("List", time.Now(), &err)
+	return r.next.List(ctx, query)
+}
+
+func (r *Repository) ListByUser(ctx context.Context, userID string) (urls []model.URL, err error) {
+	defer r.observe("ListByUser", time.Now(), &err)
+	return r.next.ListByUser(ctx, userID)
+}
+
+func (r *Repository) Update(ctx context.Context, record *model.URL) (err error) {
+	defer r.observe("Update", time.Now(), &err)
+	return r.next.Update(ctx, record)
+}
+
+func (r *Repository) Delete(ctx context.Context, id string) (err error) {
+	defer r.observe("Delete", time.Now(), &err)
+	return r.next.Delete(ctx, id)
+}
+
+func (r *Repository) Retarget(ctx context.Context, id, newURL, actor string) (u model.URL, err error) {
+	defer r.observe("Retarget", time.Now(), &err)
+	return r.next.Retarget(ctx, id, newURL, actor)
+}
+
+func (r *Repository) History(ctx context.Context, id string) (revs []model.Revision, err error) {
+	defer r.observe("History", time.Now(), &err)
+	return r.next.History(ctx, id)
+}
+
+func (r *Repository) RegisterClick(ctx context.Context, id string) (err error) {
+	defer r.observe("RegisterClick", time.Now(), &err)
+	return r.next.RegisterClick(ctx, id)
+}
+
+func (r *Repository) CreateAPIKey(ctx context.Context, key *model.APIKey) (err error) {
+	defer r.observe("CreateAPIKey", time.Now(), &err)
+	return r.next.CreateAPIKey(ctx, key)
+}
+
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, hash string) (key model.APIKey, err error) {
+	defer r.observe("GetAPIKeyByHash", time.Now(), &err)
+	return r.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (r *Repository) ListAPIKeys(ctx context.Context, userID string) (keys []model.APIKey, err error) {
+	defer r.observe("ListAPIKeys", time.Now(), &err)
+	return r.next.ListAPIKeys(ctx, userID)
+}
+
+func (r *Repository) RevokeAPIKey(ctx context.Context, userID, id string) (err error) {
+	defer r.observe("RevokeAPIKey", time.Now(), &err)
+	return r.next.RevokeAPIKey(ctx, userID, id)
+}
+
+func (r *Repository) Count(ctx context.Context) (n int, err error) {
+	defer r.observe("Count", time.Now(), &err)
+	return r.next.Count(ctx)
+}
+
+func (r *Repository) Usage(ctx context.Context, userID string, day time.Time) (u model.Usage, err error) {
+	defer r.observe("Usage", time.Now(), &err)
+	return r.next.Usage(ctx, userID, day)
+}
+
+func (r *Repository) CreateWithQuota(ctx context.Context, urls []model.URL, quota model.Quota) (err error) {
+	defer r.observe("CreateWithQuota", time.Now(), &err)
+	return r.next.CreateWithQuota(ctx, urls, quota)
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/IgorGreusunset/shortener/internal/auth"
+)
+
+// Middleware для проверки доступа администратора: статический bearer-токен, ключ API или JWT с правом admin
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		check := func(w http.ResponseWriter, r *http.Request) {
+			if auth.HasScope(r.Context(), auth.ScopeAdmin) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			//Ключ API или JWT без права admin опознан, но доступа не дает
+			if auth.IsBearer(r.Context()) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="admin"`)
+				http.Error(w, "Forbidden: admin scope required", http.StatusForbidden)
+				return
+			}
+
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			//Токен администратора дает все права
+			h.ServeHTTP(w, r.WithContext(auth.WithScopes(r.Context(), auth.Scopes)))
+		}
+
+		return http.HandlerFunc(check)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+)
+
+// Middleware для аутентификации машинных клиентов по ключу API из заголовка Authorization: Bearer.
+// Запрос выполняется от имени владельца ключа с правами ключа. Bearer-токены другого формата,
+// например токен администратора, пропускаются без изменений
+func WithAPIKey(db storage.Repository) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		keyFn := func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !auth.IsAPIKey(token) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := db.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(token))
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				logger.FromContext(r.Context()).Errorf("Error during API key lookup: %v", err)
+				http.Error(w, "Failed to check API key", http.StatusInternalServerError)
+				return
+			}
+			if err != nil || key.Revoked != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := auth.WithUser(r.Context(), key.UserID)
+			ctx = auth.WithScopes(ctx, key.Scopes)
+			ctx = auth.WithAPIKey(ctx, key.ID)
+			ctx = auth.WithMethod(ctx, auth.MethodAPIKey)
+			h.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(keyFn)
+	}
+}
+
+// Middleware для проверки права доступа у пользователя или ключа API
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		check := func(w http.ResponseWriter, r *http.Request) {
+			if !auth.HasScope(r.Context(), scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				http.Error(w, "Forbidden: "+scope+" scope required", http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(check)
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+)
+
+func TestWithAPIKey(t *testing.T) {
+	db := storage.NewStorage(map[string]model.URL{})
+
+	active, _ := auth.NewAPIKey()
+	db.CreateAPIKey(context.Background(), &model.APIKey{
+		ID:     "active",
+		UserID: "robot",
+		Hash:   auth.HashAPIKey(active),
+		Scopes: []string{auth.ScopeRead},
+	})
+	revoked, _ := auth.NewAPIKey()
+	db.CreateAPIKey(context.Background(), &model.APIKey{
+		ID:     "revoked",
+		UserID: "robot",
+		Hash:   auth.HashAPIKey(revoked),
+		Scopes: []string{auth.ScopeRead},
+	})
+	db.RevokeAPIKey(context.Background(), "robot", "revoked")
+	unknown, _ := auth.NewAPIKey()
+
+	tests := []struct {
+		name          string
+		authorization string
+		scope         string
+		expectedCode  int
+		expectedUser  string
+	}{
+		{
+			name:          "active",
+			authorization: "Bearer " + active,
+			scope:         auth.ScopeRead,
+			expectedCode:  http.StatusOK,
+			expectedUser:  "robot",
+		},
+		{
+			name:          "missing_scope",
+			authorization: "Bearer " + active,
+			scope:         auth.ScopeDelete,
+			expectedCode:  http.StatusForbidden,
+		},
+		{
+			name:          "revoked",
+			authorization: "Bearer " + revoked,
+			scope:         auth.ScopeRead,
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "unknown",
+			authorization: "Bearer " + unknown,
+			scope:         auth.ScopeRead,
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			//Другие bearer-токены обрабатывают следующие middleware, здесь пользователь определяется по cookie
+			name:          "not_api_key",
+			authorization: "Bearer admin-token",
+			scope:         auth.ScopeRead,
+			expectedCode:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userID string
+			var cookieSet bool
+			final := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				userID, _ = auth.UserFromContext(req.Context())
+				cookieSet = res.Header().Get("Set-Cookie") != ""
+			})
+			h := WithAPIKey(db)(WithAuth([]byte("secret"))(RequireScope(tt.scope)(final)))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d", rr.Code, tt.expectedCode)
+			}
+			if tt.expectedUser != "" {
+				if userID != tt.expectedUser {
+					t.Errorf("User didn't match expected: got %s want %s", userID, tt.expectedUser)
+				}
+				if cookieSet {
+					t.Errorf("Cookie must not be issued for API key requests")
+				}
+			}
+		})
+	}
+}
+
+func TestAdminAuthWithAPIKey(t *testing.T) {
+	db := storage.NewStorage(map[string]model.URL{})
+
+	admin, _ := auth.NewAPIKey()
+	db.CreateAPIKey(context.Background(), &model.APIKey{ID: "admin", UserID: "ops", Hash: auth.HashAPIKey(admin), Scopes: []string{auth.ScopeAdmin}, Created: time.Now()})
+	reader, _ := auth.NewAPIKey()
+	db.CreateAPIKey(context.Background(), &model.APIKey{ID: "reader", UserID: "ops", Hash: auth.HashAPIKey(reader), Scopes: []string{auth.ScopeRead}, Created: time.Now()})
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+	}{
+		{name: "admin_key", authorization: "Bearer " + admin, expectedCode: http.StatusOK},
+		{name: "admin_token", authorization: "Bearer admin-token", expectedCode: http.StatusOK},
+		{name: "reader_key", authorization: "Bearer " + reader, expectedCode: http.StatusForbidden},
+		{name: "wrong_token", authorization: "Bearer nope", expectedCode: http.StatusUnauthorized},
+	}
+
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	h := WithAPIKey(db)(WithAuth([]byte("secret"))(AdminAuth("admin-token")(ok)))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/urls", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Errorf("Response code didn't match expected: got %d want %d", rr.Code, tt.expectedCode)
+			}
+		})
+	}
+}
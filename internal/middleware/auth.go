@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/IgorGreusunset/shortener/internal/auth"
+)
+
+// Middleware для идентификации пользователя по подписанной cookie. Если cookie нет или подпись неверна, выдается новая.
+// Запросы, уже аутентифицированные bearer-токеном, пропускаются без cookie
+func WithAuth(key []byte) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		authFn := func(w http.ResponseWriter, r *http.Request) {
+			if auth.IsBearer(r.Context()) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			var userID string
+
+			if c, err := r.Cookie(auth.CookieName); err == nil {
+				userID, _ = auth.Verify(c.Value, key)
+			}
+
+			if userID == "" {
+				userID = auth.NewUserID()
+				http.SetCookie(w, &http.Cookie{
+					Name:     auth.CookieName,
+					Value:    auth.Sign(userID, key),
+					Path:     "/",
+					HttpOnly: true,
+				})
+			}
+
+			ctx := auth.WithUser(r.Context(), userID)
+			ctx = auth.WithScopes(ctx, auth.UserScopes)
+			h.ServeHTTP(w, r.WithContext(auth.WithMethod(ctx, auth.MethodCookie)))
+		}
+
+		return http.HandlerFunc(authFn)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Максимальная длина subject: идентификатор становится владельцем ссылок и хранится в user_id
+const maxSubjectLength = 50
+
+// Настройки проверки JWT поставщика удостоверений
+type JWTOptions struct {
+	Keys *auth.JWKS
+	//Пустые значения не проверяются
+	Audience string
+	Issuer   string
+	//Допустимое расхождение часов при проверке exp, nbf и iat
+	Leeway time.Duration
+}
+
+// Claims токена: стандартные и права доступа в claim scope через пробел
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope,omitempty"`
+}
+
+// Middleware для аутентификации по JWT из заголовка Authorization: Bearer. Проверяются подпись (HS256, RS256),
+// срок действия, audience и issuer. Владельцем ссылок становится subject токена. Без claim scope токен получает
+// права пользователя по cookie. Bearer-токены другого формата пропускаются без изменений
+func WithJWT(opts JWTOptions) func(http.Handler) http.Handler {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	parser := jwt.NewParser(parserOpts...)
+
+	keyFunc := func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return opts.Keys.Key(kid, t.Method.Alg())
+	}
+
+	return func(h http.Handler) http.Handler {
+		jwtFn := func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || auth.IsAPIKey(token) || strings.Count(token, ".") != 2 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			var claims jwtClaims
+			if _, err := parser.ParseWithClaims(token, &claims, keyFunc); err != nil {
+				logger.FromContext(r.Context()).Debugw("invalid JWT", "error", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+			if claims.Subject == "" || len(claims.Subject) > maxSubjectLength {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid token subject", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := auth.WithUser(r.Context(), claims.Subject)
+			ctx = auth.WithScopes(ctx, jwtScopes(claims.Scope))
+			ctx = auth.WithMethod(ctx, auth.MethodJWT)
+			h.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(jwtFn)
+	}
+}
+
+// Права доступа из claim scope. Неизвестные значения, например права других сервисов, пропускаются
+func jwtScopes(claim string) []string {
+	if claim == "" {
+		return auth.UserScopes
+	}
+
+	var scopes []string
+	for _, s := range strings.Fields(claim) {
+		if auth.ValidScope(s) && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
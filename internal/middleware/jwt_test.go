@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestWithJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	//Набор ключей в том виде, в каком его публикует поставщик удостоверений
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "oct", "kid": "hs", "alg": "HS256", "k": b64(secret)},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(mod func(c jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub": "alice",
+			"aud": "shortener",
+			"iss": "https://id.example.com",
+			"iat": now.Unix(),
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, kid string, key any, c jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, c)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+
+	tests := []struct {
+		name         string
+		token        string
+		scope        string
+		expectedCode int
+		expectedUser string
+	}{
+		{
+			name:         "rs256",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)),
+			expectedCode: http.StatusOK,
+			expectedUser: "alice",
+		},
+		{
+			name:         "hs256",
+			token:        sign(jwt.SigningMethodHS256, "hs", secret, claims(nil)),
+			expectedCode: http.StatusOK,
+			expectedUser: "alice",
+		},
+		{
+			name:         "expired",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() })),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "no_expiry",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "wrong_audience",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["aud"] = "billing" })),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "wrong_issuer",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "foreign_key",
+			token:        sign(jwt.SigningMethodRS256, "rsa", otherKey, claims(nil)),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "unknown_kid",
+			token:        sign(jwt.SigningMethodRS256, "other", rsaKey, claims(nil)),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			//Открытый ключ RSA известен всем и не должен приниматься как секрет HS256
+			name:         "algorithm_confusion",
+			token:        sign(jwt.SigningMethodHS256, "rsa", pubDER, claims(nil)),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "alg_none",
+			token:        sign(jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "long_subject",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["sub"] = strings.Repeat("a", maxSubjectLength+1) })),
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "scope_claim",
+			token:        sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["scope"] = "openid read" })),
+			scope:        auth.ScopeShorten,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			//Токен администратора не является JWT и обрабатывается AdminAuth
+			name:         "not_jwt",
+			token:        "admin-token",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := tt.scope
+			if scope == "" {
+				scope = auth.ScopeRead
+			}
+
+			var userID string
+			var cookieSet bool
+			final := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				userID, _ = auth.UserFromContext(req.Context())
+				cookieSet = res.Header().Get("Set-Cookie") != ""
+			})
+			h := WithJWT(JWTOptions{
+				Keys:     keys,
+				Audience: "shortener",
+				Issuer:   "https://id.example.com",
+				Leeway:   time.Second,
+			})(WithAuth([]byte("secret"))(RequireScope(scope)(final)))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedCode {
+				t.Fatalf("Response code didn't match expected: got %d want %d (%s)", rr.Code, tt.expectedCode, rr.Body.String())
+			}
+			if tt.expectedUser != "" {
+				if userID != tt.expectedUser {
+					t.Errorf("User didn't match expected: got %s want %s", userID, tt.expectedUser)
+				}
+				if cookieSet {
+					t.Errorf("Cookie must not be issued for JWT requests")
+				}
+			}
+		})
+	}
+}
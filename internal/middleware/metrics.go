@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/internal/metrics"
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware для сбора метрик запросов. Метки строятся по шаблону маршрута chi, а не по URI,
+// чтобы число рядов не росло с числом коротких ссылок
+func WithMetrics(h http.Handler) http.Handler {
+	metricsFn := func(res http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		responseData := &responseData{}
+		lw := loggingResponseWriter{
+			ResponseWriter: res,
+			responseData:   responseData,
+		}
+
+		h.ServeHTTP(&lw, req)
+
+		//Шаблон маршрута известен только после того, как chi выполнил маршрутизацию
+		route := "unmatched"
+		if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := responseData.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequests.WithLabelValues(route, req.Method, strconv.Itoa(status)).Inc()
+		metrics.HTTPDuration.WithLabelValues(route, req.Method).Observe(time.Since(start).Seconds())
+	}
+	return http.HandlerFunc(metricsFn)
+}
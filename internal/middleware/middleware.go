@@ -5,8 +5,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/IgorGreusunset/shortener/cmd/config"
 	"github.com/IgorGreusunset/shortener/internal/compress"
 	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/metrics"
 )
 
 func WithLogging(h http.Handler) http.Handler {
@@ -32,8 +34,8 @@ func WithLogging(h http.Handler) http.Handler {
 		//Фиксируем время выполнения запроса
 		duration := time.Since(start)
 
-		//Записываем информацию о запросе в логгер
-		logger.Log.Infoln(
+		//Записываем информацию о запросе в логгер запроса, чтобы строка содержала его идентификатор
+		logger.FromContext(req.Context()).Infow("request",
 			"uri", uri,
 			"method", method,
 			"status", responseData.status,
@@ -75,29 +77,31 @@ func GzipMiddleware(h http.Handler) http.Handler{
 	comp := func(w http.ResponseWriter, r *http.Request) {
 		ow := w
 
-		content := w.Header().Get("Content-Type")
-
-		//Проверяем формат контента и сжимаем, если контент разрешенного типа
-		if content == "application/json" || content == "text/html" {
-			acceptEncoding := r.Header.Get("Accept-Encoding")
-			if strings.Contains(acceptEncoding, "gzip"){
-				cw := compress.NewCompressWrite(w)
-				ow = cw
-				defer cw.Close()			
-			}
+		//Тип контента известен только после того, как обработчик выставит заголовки,
+		//поэтому сжатие решается в CompressWriter при записи ответа
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			cw := compress.NewCompressWrite(w, "application/json", "text/html")
+			ow = cw
+			defer func() {
+				cw.Close()
+				if ratio, ok := cw.Ratio(); ok {
+					metrics.GzipRatio.Observe(ratio)
+				}
+			}()
 		}
 
 		//Проверяем, сжато ли тело запроса и декодируем, если да
 		contentEncoding := r.Header.Get("Content-Encoding")
 
 		if strings.Contains(contentEncoding, "gzip") {
-			cr, err := compress.NewCompressReader(r.Body)
+			cr, err := compress.NewCompressReader(http.MaxBytesReader(w, r.Body, config.MaxBodySize))
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 			defer cr.Close()
-			r.Body = cr
+			//Ограничиваем размер распакованных данных, чтобы маленький архив не раздулся в памяти
+			r.Body = http.MaxBytesReader(w, cr, config.MaxBodySize)
 		}
 
 		h.ServeHTTP(ow, r)
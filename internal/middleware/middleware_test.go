@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IgorGreusunset/shortener/internal/metrics"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestGzipMiddleware(t *testing.T) {
+	body := strings.Repeat(`{"result":"http://localhost:8080/EwHXdJfB"}`, 50)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		acceptEncoding string
+		status         int
+		compressed     bool
+	}{
+		{name: "json", contentType: "application/json", acceptEncoding: "gzip", status: http.StatusOK, compressed: true},
+		{name: "html_with_charset", contentType: "text/html; charset=utf-8", acceptEncoding: "gzip, deflate", status: http.StatusOK, compressed: true},
+		{name: "plain_text", contentType: "text/plain", acceptEncoding: "gzip", status: http.StatusOK},
+		{name: "no_accept_encoding", contentType: "application/json", status: http.StatusOK},
+		{name: "error_status", contentType: "application/json", acceptEncoding: "gzip", status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := gzipRatioCount(t)
+
+			h := GzipMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Content-Type", tt.contentType)
+				res.WriteHeader(tt.status)
+				io.WriteString(res, body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Response code didn't match expected: got %d want %d", w.Code, tt.status)
+			}
+
+			got := w.Body.String()
+			if tt.compressed {
+				if enc := w.Header().Get("Content-Encoding"); enc != "gzip" {
+					t.Fatalf("Content-Encoding didn't match expected: got %q want gzip", enc)
+				}
+				gr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("Failed to read gzip body: %v", err)
+				}
+				b, err := io.ReadAll(gr)
+				if err != nil {
+					t.Fatalf("Failed to read gzip body: %v", err)
+				}
+				got = string(b)
+			} else if enc := w.Header().Get("Content-Encoding"); enc != "" {
+				t.Errorf("Unexpected Content-Encoding: %q", enc)
+			}
+			if got != body {
+				t.Errorf("Body didn't match expected after decompression")
+			}
+
+			//Степень сжатия записывается только для сжатых ответов
+			observed := gzipRatioCount(t) - before
+			if tt.compressed && observed != 1 {
+				t.Errorf("Gzip ratio observations didn't match expected: got %d want 1", observed)
+			}
+			if !tt.compressed && observed != 0 {
+				t.Errorf("Gzip ratio observations didn't match expected: got %d want 0", observed)
+			}
+		})
+	}
+}
+
+// Число наблюдений гистограммы степени сжатия
+func gzipRatioCount(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.GzipRatio.Write(&m); err != nil {
+		t.Fatalf("Failed to read gzip ratio: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
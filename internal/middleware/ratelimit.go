@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/internal/auth"
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/ratelimit"
+)
+
+// Способы определения клиента для ограничения частоты запросов
+const (
+	RateLimitByIP   = "ip"
+	RateLimitByUser = "user"
+)
+
+// Проверяет, что способ определения клиента известен
+func IsRateLimitKey(keyBy string) bool {
+	return keyBy == RateLimitByIP || keyBy == RateLimitByUser
+}
+
+// Middleware для ограничения частоты запросов клиента. scope разделяет корзины разных групп маршрутов.
+// При ключе по пользователю клиенты без действующей cookie ограничиваются по IP
+func RateLimit(store ratelimit.Store, limit ratelimit.Limit, scope, keyBy string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		limitFn := func(w http.ResponseWriter, r *http.Request) {
+			res, err := store.Take(r.Context(), scope+":"+clientKey(r, keyBy), limit)
+			if err != nil {
+				//Недоступность хранилища не должна останавливать сервис
+				logger.FromContext(r.Context()).Errorf("Error during rate limit check: %v", err)
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(limitFn)
+	}
+}
+
+// Определяет ключ клиента: пользователь с действующей cookie, ключом API или JWT, либо IP-адрес
+func clientKey(r *http.Request, keyBy string) string {
+	if keyBy == RateLimitByUser {
+		userID, ok := auth.UserFromContext(r.Context())
+		if ok && auth.IsBearer(r.Context()) {
+			return "user:" + userID
+		}
+		//Новую cookie можно получать на каждый запрос, поэтому учитываем только уже выданную
+		if c, err := r.Cookie(auth.CookieName); ok && err == nil && strings.HasPrefix(c.Value, userID+".") {
+			return "user:" + userID
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
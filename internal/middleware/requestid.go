@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// Максимальная длина идентификатора, принимаемого от клиента
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// Middleware, присваивающее запросу идентификатор. Идентификатор из X-Request-ID используется, если он корректен,
+// иначе генерируется новый. Он возвращается в заголовке ответа, в том числе с ошибками,
+// и добавляется ко всем строкам лога через логгер в контексте запроса
+func WithRequestID(h http.Handler) http.Handler {
+	idFn := func(res http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		res.Header().Set(RequestIDHeader, id)
+
+		l := logger.Log.With("request_id", id)
+		//Связываем лог с трассой, если запрос трассируется
+		if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+			l = l.With("trace_id", sc.TraceID().String())
+		}
+
+		ctx := context.WithValue(req.Context(), requestIDKey{}, id)
+		ctx = logger.WithContext(ctx, l)
+
+		h.ServeHTTP(res, req.WithContext(ctx))
+	}
+	return http.HandlerFunc(idFn)
+}
+
+// Идентификатор текущего запроса, пустая строка вне WithRequestID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Принимаем от клиента только короткие идентификаторы из безопасных символов, чтобы не портить логи
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{
+			name:     "from_client",
+			header:   "req-42",
+			expected: "req-42",
+		},
+		{
+			name:   "generated",
+			header: "",
+		},
+		{
+			name:   "invalid",
+			header: "bad id\n",
+		},
+		{
+			name:   "too_long",
+			header: strings.Repeat("a", maxRequestIDLength+1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromCtx string
+			h := WithRequestID(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				fromCtx = RequestID(req.Context())
+				http.Error(res, "Not found", http.StatusNotFound)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			got := w.Result().Header.Get(RequestIDHeader)
+			if got != fromCtx {
+				t.Errorf("Response id didn't match context id: got %s want %s", got, fromCtx)
+			}
+			if tt.expected != "" && got != tt.expected {
+				t.Errorf("Request id didn't match expected: got %s want %s", got, tt.expected)
+			}
+			if tt.expected == "" && (got == "" || got == tt.header) {
+				t.Errorf("Expected generated request id, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+)
+
+// Middleware для определения тенанта по заголовку Host. Запросы на неизвестные домены относятся к тенанту по умолчанию
+func WithTenant(tenants *tenant.Registry) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		tenantFn := func(w http.ResponseWriter, r *http.Request) {
+			t := tenants.Resolve(r.Host)
+
+			ctx := tenant.WithTenant(r.Context(), t)
+			if t.ID != "" {
+				ctx = logger.WithContext(ctx, logger.FromContext(ctx).With("tenant", t.ID))
+			}
+			h.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(tenantFn)
+	}
+}
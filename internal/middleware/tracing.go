@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/IgorGreusunset/shortener/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware трассировки: продолжает трассу из заголовка traceparent и открывает серверный span на запрос.
+// Имя span строится по шаблону маршрута chi, а не по URI, как и метки метрик
+func WithTracing(h http.Handler) http.Handler {
+	tracingFn := func(res http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+		ctx, span := tracing.Tracer().Start(ctx, req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("url.path", req.URL.Path),
+				attribute.String("user_agent.original", req.UserAgent()),
+			))
+		defer span.End()
+
+		responseData := &responseData{}
+		lw := loggingResponseWriter{
+			ResponseWriter: res,
+			responseData:   responseData,
+		}
+
+		h.ServeHTTP(&lw, req.WithContext(ctx))
+
+		//Шаблон маршрута известен только после того, как chi выполнил маршрутизацию
+		if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(req.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+
+		status := responseData.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+	return http.HandlerFunc(tracingFn)
+}
@@ -0,0 +1,203 @@
+package openapi
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Ресурсы Swagger UI из swagger-ui-dist 5 (лицензия Apache 2.0 в swagger-ui/LICENSE). Встроены в бинарный файл,
+// чтобы документация открывалась без доступа к CDN и браузер не выполнял сторонний код с чужого домена
+//
+//go:embed swagger-ui/swagger-ui-bundle.js swagger-ui/swagger-ui.css
+var swaggerUI embed.FS
+
+var (
+	loadOnce sync.Once
+	spec     *openapi3.T
+	specJSON []byte
+	loadErr  error
+)
+
+// Разобранная и проверенная спецификация API, встроенная в бинарный файл
+func Spec() (*openapi3.T, error) {
+	loadOnce.Do(func() {
+		loader := openapi3.NewLoader()
+		spec, loadErr = loader.LoadFromData(specYAML)
+		if loadErr != nil {
+			return
+		}
+		if loadErr = spec.Validate(context.Background()); loadErr != nil {
+			return
+		}
+		specJSON, loadErr = json.Marshal(spec)
+	})
+	return spec, loadErr
+}
+
+// Handler для отдачи спецификации в JSON
+func Handler() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if _, err := Spec(); err != nil {
+			http.Error(res, "Invalid OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusOK)
+		res.Write(specJSON)
+	}
+}
+
+// Страница Swagger UI, ресурсы интерфейса отдает AssetsHandler
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>URL shortener API</title>
+<link rel="stylesheet" href="/api/docs/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="/api/docs/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui" });
+</script>
+</body>
+</html>
+`
+
+// Handler для страницы Swagger UI
+func DocsHandler() http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/html")
+		res.WriteHeader(http.StatusOK)
+		res.Write([]byte(docsPage))
+	}
+}
+
+// Handler для встроенных ресурсов Swagger UI. Ресурсы меняются только с новой версией сервиса
+func AssetsHandler() http.HandlerFunc {
+	assets, err := fs.Sub(swaggerUI, "swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix("/api/docs/", http.FileServer(http.FS(assets)))
+
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Cache-Control", "public, max-age=86400")
+		files.ServeHTTP(res, req)
+	}
+}
+
+// Middleware для проверки запроса по спецификации: параметров пути и запроса, заголовков и тела.
+// Операция находится по шаблону маршрута chi, поэтому middleware подключается к группе маршрутов,
+// где маршрутизация уже выполнена. Маршруты без описания в спецификации пропускаются
+func Validate(h http.Handler) http.Handler {
+	validateFn := func(res http.ResponseWriter, req *http.Request) {
+		doc, err := Spec()
+		if err != nil {
+			http.Error(res, "Invalid OpenAPI document", http.StatusInternalServerError)
+			return
+		}
+
+		rctx := chi.RouteContext(req.Context())
+		if rctx == nil {
+			h.ServeHTTP(res, req)
+			return
+		}
+		pattern := rctx.RoutePattern()
+		pathItem := doc.Paths.Find(pattern)
+		if pathItem == nil || pathItem.GetOperation(req.Method) == nil {
+			h.ServeHTTP(res, req)
+			return
+		}
+
+		params := make(map[string]string, len(rctx.URLParams.Keys))
+		for i, key := range rctx.URLParams.Keys {
+			params[key] = rctx.URLParams.Values[i]
+		}
+
+		//Тело читается до handler, поэтому ограничиваем его здесь так же, как в handlers
+		if req.Body != nil {
+			req.Body = http.MaxBytesReader(res, req.Body, config.MaxBodySize)
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: params,
+			Route: &routers.Route{
+				Spec:      doc,
+				Path:      pattern,
+				PathItem:  pathItem,
+				Method:    req.Method,
+				Operation: pathItem.GetOperation(req.Method),
+			},
+			Options: &openapi3filter.Options{
+				//Аутентификацию выполняют middleware сервиса
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+			writeValidationError(res, err)
+			return
+		}
+
+		h.ServeHTTP(res, req)
+	}
+	return http.HandlerFunc(validateFn)
+}
+
+// Записывает ответ для запроса, не прошедшего проверку
+func writeValidationError(res http.ResponseWriter, err error) {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		http.Error(res, fmt.Sprintf("Request body too large: limit is %d bytes", mbe.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	status := http.StatusBadRequest
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) && reqErr.RequestBody != nil && isContentTypeError(reqErr) {
+		status = http.StatusUnsupportedMediaType
+	}
+	http.Error(res, "Request doesn't match API schema: "+errorSummary(err), status)
+}
+
+// Тело запроса передано в формате, не описанном в спецификации
+func isContentTypeError(err *openapi3filter.RequestError) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "unsupported content type") || strings.Contains(msg, "header Content-Type has unexpected value")
+}
+
+// Краткое описание ошибки проверки без дампа схемы
+func errorSummary(err error) string {
+	var reqErr *openapi3filter.RequestError
+	if errors.As(err, &reqErr) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(reqErr.Err, &schemaErr) {
+			field := strings.Join(schemaErr.JSONPointer(), ".")
+			if field != "" {
+				return fmt.Sprintf("%s: %s", field, schemaErr.Reason)
+			}
+			return schemaErr.Reason
+		}
+		return reqErr.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,125 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		expected    int
+	}{
+		{
+			name:        "valid",
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{"url":"https://practicum.yandex.ru"}`,
+			expected:    http.StatusCreated,
+		},
+		{
+			name:        "unknown_field",
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{"url":"https://practicum.yandex.ru","foo":1}`,
+			expected:    http.StatusBadRequest,
+		},
+		{
+			name:        "missing_url",
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{}`,
+			expected:    http.StatusBadRequest,
+		},
+		{
+			name:        "wrong_content_type",
+			path:        "/api/shorten",
+			contentType: "application/xml",
+			body:        `<url/>`,
+			expected:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "too_large",
+			path:        "/api/shorten",
+			contentType: "application/json",
+			body:        `{"url":"https://practicum.yandex.ru/` + strings.Repeat("a", 2<<20) + `"}`,
+			expected:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "undocumented",
+			path:        "/api/other",
+			contentType: "text/plain",
+			body:        `anything`,
+			expected:    http.StatusCreated,
+		},
+	}
+
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(Validate)
+		created := func(res http.ResponseWriter, _ *http.Request) { res.WriteHeader(http.StatusCreated) }
+		r.Post(`/api/shorten`, created)
+		r.Post(`/api/other`, created)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Response code didn't match expected: got %d want %d (%s)", rr.Code, tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDocsAssets(t *testing.T) {
+	router := chi.NewRouter()
+	router.Get(`/api/docs`, DocsHandler())
+	router.Get(`/api/docs/{file}`, AssetsHandler())
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if strings.Contains(rr.Body.String(), "https://") {
+		t.Errorf("Docs page loads resources from another host: %s", rr.Body.String())
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		expected    int
+		contentType string
+	}{
+		{name: "bundle", path: "/api/docs/swagger-ui-bundle.js", expected: http.StatusOK, contentType: "text/javascript"},
+		{name: "styles", path: "/api/docs/swagger-ui.css", expected: http.StatusOK, contentType: "text/css"},
+		{name: "missing", path: "/api/docs/swagger-ui.js", expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rr.Code != tt.expected {
+				t.Fatalf("Response code didn't match expected: got %d want %d", rr.Code, tt.expected)
+			}
+			if !strings.HasPrefix(rr.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("Content-Type didn't match expected: got %s want %s", rr.Header().Get("Content-Type"), tt.contentType)
+			}
+			if tt.expected == http.StatusOK && !strings.Contains(rr.Body.String(), "swagger") {
+				t.Errorf("Body doesn't look like swagger-ui asset")
+			}
+		})
+	}
+}
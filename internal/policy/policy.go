@@ -0,0 +1,243 @@
+package policy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/internal/logger"
+	"github.com/IgorGreusunset/shortener/internal/urlnorm"
+)
+
+var (
+	ErrBlocked    = errors.New("destination is blocked")
+	ErrNotAllowed = errors.New("destination is not in allowlist")
+)
+
+// Политика по умолчанию, используется обработчиками. Пока файлы не заданы, разрешает все ссылки
+var Default = &Engine{}
+
+// Набор правил из одного файла. Формат строки:
+//
+//	example.com      - точное совпадение хоста
+//	*.example.com    - любой поддомен example.com (сам example.com не включается)
+//	/^ads\d+\./      - регулярное выражение для хоста
+//	10.0.0.0/8       - диапазон для ссылок с IP-адресом вместо хоста
+//
+// Пустые строки и строки, начинающиеся с #, пропускаются
+type Rules struct {
+	hosts    map[string]bool
+	suffixes []string
+	patterns []*regexp.Regexp
+	networks []*net.IPNet
+}
+
+// Разбирает правила из r
+func ParseRules(r io.Reader) (*Rules, error) {
+	rules := &Rules{hosts: map[string]bool{}}
+
+	scan := bufio.NewScanner(r)
+	for n := 1; scan.Scan(); n++ {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case len(line) > 1 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/"):
+			re, err := regexp.Compile(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			rules.patterns = append(rules.patterns, re)
+		case strings.Contains(line, "/"):
+			_, network, err := net.ParseCIDR(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			rules.networks = append(rules.networks, network)
+		case strings.HasPrefix(line, "*."):
+			host, err := urlnorm.NormalizeHost(line[2:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			rules.suffixes = append(rules.suffixes, "."+host)
+		default:
+			host, err := urlnorm.NormalizeHost(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			rules.hosts[host] = true
+		}
+	}
+
+	return rules, scan.Err()
+}
+
+// Проверяет, подпадает ли хост под одно из правил
+func (r *Rules) Match(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range r.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	if r.hosts[host] {
+		return true
+	}
+
+	for _, suffix := range r.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	for _, re := range r.patterns {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Политика проверки ссылок по черному и белому спискам.
+// Белый список включает корпоративный режим: разрешены только перечисленные в нем хосты
+type Engine struct {
+	mu        sync.RWMutex
+	blockPath string
+	allowPath string
+	block     *Rules
+	allow     *Rules
+	modTimes  map[string]time.Time
+}
+
+// Создает политику по файлам списков, пустой путь отключает соответствующий список
+func New(blockPath, allowPath string) (*Engine, error) {
+	e := &Engine{blockPath: blockPath, allowPath: allowPath}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Перечитывает файлы списков. При ошибке сохраняются прежние правила
+func (e *Engine) Reload() error {
+	modTimes := map[string]time.Time{}
+
+	block, err := loadRules(e.blockPath, modTimes)
+	if err != nil {
+		return fmt.Errorf("blocklist: %w", err)
+	}
+	allow, err := loadRules(e.allowPath, modTimes)
+	if err != nil {
+		return fmt.Errorf("allowlist: %w", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.block = block
+	e.allow = allow
+	e.modTimes = modTimes
+	return nil
+}
+
+// Периодически проверяет время изменения файлов и перечитывает их при изменении
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !e.changed() {
+				continue
+			}
+			if err := e.Reload(); err != nil {
+				logger.Log.Errorf("Error during policy reload: %v", err)
+				continue
+			}
+			logger.Log.Infoln("Policy lists reloaded")
+		}
+	}
+}
+
+// Проверяет полную ссылку, возвращает ErrBlocked или ErrNotAllowed, если переход по ней запрещен
+func (e *Engine) Check(rawURL string) error {
+	e.mu.RLock()
+	block, allow := e.block, e.allow
+	e.mu.RUnlock()
+
+	if block == nil && allow == nil {
+		return nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrBlocked
+	}
+	host, err := urlnorm.NormalizeHost(u.Hostname())
+	if err != nil {
+		return ErrBlocked
+	}
+
+	if block != nil && block.Match(host) {
+		return ErrBlocked
+	}
+	if allow != nil && !allow.Match(host) {
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+func (e *Engine) changed() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, path := range []string{e.blockPath, e.allowPath} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(e.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadRules(path string, modTimes map[string]time.Time) (*Rules, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	modTimes[path] = info.ModTime()
+
+	return ParseRules(f)
+}
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRulesMatch(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`
+# phishing
+evil.com
+*.phish.net
+/^ads[0-9]+\./
+10.0.0.0/8
+`))
+	if err != nil {
+		t.Fatalf("Error during parsing rules: %v", err)
+	}
+
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "evil.com", expected: true},
+		{host: "www.evil.com", expected: false},
+		{host: "login.phish.net", expected: true},
+		{host: "phish.net", expected: false},
+		{host: "ads12.example.com", expected: true},
+		{host: "10.1.2.3", expected: true},
+		{host: "192.168.0.1", expected: false},
+		{host: "mail.ru", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := rules.Match(tt.host); got != tt.expected {
+				t.Errorf("Match didn't match expected: got %v want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEngine(t *testing.T) {
+	dir := t.TempDir()
+	block := filepath.Join(dir, "block.txt")
+	allow := filepath.Join(dir, "allow.txt")
+
+	if err := os.WriteFile(block, []byte("evil.com\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := New(block, "")
+	if err != nil {
+		t.Fatalf("Error during creating engine: %v", err)
+	}
+
+	if err := e.Check("https://EVIL.com/login"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected blocked destination, got %v", err)
+	}
+	if err := e.Check("https://mail.ru/"); err != nil {
+		t.Errorf("Expected allowed destination, got %v", err)
+	}
+
+	//После изменения файла правила перечитываются
+	if err := os.WriteFile(block, []byte("mail.ru\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(block, future, future)
+	if !e.changed() {
+		t.Fatalf("Expected blocklist change to be detected")
+	}
+	if err := e.Reload(); err != nil {
+		t.Fatalf("Error during reload: %v", err)
+	}
+	if err := e.Check("https://mail.ru/"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected blocked destination after reload, got %v", err)
+	}
+
+	//Корпоративный режим
+	if err := os.WriteFile(allow, []byte("*.corp.example\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	e, err = New("", allow)
+	if err != nil {
+		t.Fatalf("Error during creating engine: %v", err)
+	}
+	if err := e.Check("https://wiki.corp.example/"); err != nil {
+		t.Errorf("Expected allowed destination, got %v", err)
+	}
+	if err := e.Check("https://mail.ru/"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("Expected not allowed destination, got %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Поддерживаемые форматы изображения
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Ограничения параметров изображения
+const (
+	DefaultSize   = 256
+	MaxSize       = 2048
+	DefaultMargin = 4
+	MaxMargin     = 20
+)
+
+var (
+	ErrInvalidFormat = errors.New("invalid qr format")
+	ErrInvalidLevel  = errors.New("invalid qr error correction level")
+	ErrInvalidSize   = errors.New("invalid qr size")
+	ErrInvalidMargin = errors.New("invalid qr margin")
+)
+
+// Параметры генерации QR-кода
+type Options struct {
+	Format string
+	//Сторона изображения в пикселях
+	Size int
+	//Уровень коррекции ошибок: L, M, Q или H
+	Level string
+	//Ширина свободного поля вокруг кода в модулях
+	Margin int
+}
+
+// Параметры по умолчанию
+func DefaultOptions() Options {
+	return Options{
+		Format: FormatPNG,
+		Size:   DefaultSize,
+		Level:  "M",
+		Margin: DefaultMargin,
+	}
+}
+
+// Генерирует QR-код с содержимым content, возвращает изображение и его Content-Type
+func Encode(content string, opts Options) ([]byte, string, error) {
+	level, err := recoveryLevel(opts.Level)
+	if err != nil {
+		return nil, "", err
+	}
+	if opts.Size <= 0 || opts.Size > MaxSize {
+		return nil, "", ErrInvalidSize
+	}
+	if opts.Margin < 0 || opts.Margin > MaxMargin {
+		return nil, "", ErrInvalidMargin
+	}
+
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, "", err
+	}
+	q.DisableBorder = true
+	bitmap := withMargin(q.Bitmap(), opts.Margin)
+
+	switch opts.Format {
+	case FormatPNG:
+		data, err := renderPNG(bitmap, opts.Size)
+		return data, "image/png", err
+	case FormatSVG:
+		return renderSVG(bitmap, opts.Size), "image/svg+xml", nil
+	default:
+		return nil, "", ErrInvalidFormat
+	}
+}
+
+func recoveryLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qrcode.Low, nil
+	case "M", "":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	}
+	return 0, ErrInvalidLevel
+}
+
+// Добавляет вокруг кода свободное поле заданной ширины
+func withMargin(bitmap [][]bool, margin int) [][]bool {
+	n := len(bitmap) + 2*margin
+	result := make([][]bool, n)
+	for y := range result {
+		result[y] = make([]bool, n)
+	}
+	for y, row := range bitmap {
+		copy(result[y+margin][margin:], row)
+	}
+	return result
+}
+
+// Растеризует код в PNG со стороной size пикселей. Размер модуля целый, остаток распределяется по краям
+func renderPNG(bitmap [][]bool, size int) ([]byte, error) {
+	n := len(bitmap)
+	if size < n {
+		size = n
+	}
+	scale := size / n
+	offset := (size - scale*n) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y, row := range bitmap {
+		for x, black := range row {
+			if !black {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Формирует SVG, в котором каждый модуль - квадрат единичного размера
+func renderSVG(bitmap [][]bool, size int) []byte {
+	n := len(bitmap)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, n, n)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, n, n)
+	for y, row := range bitmap {
+		for x, black := range row {
+			if black {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.Bytes()
+}
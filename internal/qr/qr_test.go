@@ -0,0 +1,75 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectedErr error
+		expectedCT  string
+	}{
+		{
+			name:       "png",
+			opts:       DefaultOptions(),
+			expectedCT: "image/png",
+		},
+		{
+			name:       "svg",
+			opts:       Options{Format: FormatSVG, Size: 128, Level: "H", Margin: 0},
+			expectedCT: "image/svg+xml",
+		},
+		{
+			name:        "bad_level",
+			opts:        Options{Format: FormatPNG, Size: 128, Level: "X"},
+			expectedErr: ErrInvalidLevel,
+		},
+		{
+			name:        "too_big",
+			opts:        Options{Format: FormatPNG, Size: MaxSize + 1},
+			expectedErr: ErrInvalidSize,
+		},
+		{
+			name:        "bad_format",
+			opts:        Options{Format: "gif", Size: 128},
+			expectedErr: ErrInvalidFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, ct, err := Encode("http://localhost:8080/U8rtGB25", tt.opts)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Error didn't match expected: got %v want %v", err, tt.expectedErr)
+			}
+			if tt.expectedErr != nil {
+				return
+			}
+
+			if ct != tt.expectedCT {
+				t.Errorf("Content-type didn't match expected: got %v want %v", ct, tt.expectedCT)
+			}
+
+			switch tt.opts.Format {
+			case FormatPNG:
+				img, err := png.Decode(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("Error during decoding png: %v", err)
+				}
+				if img.Bounds().Dx() != tt.opts.Size {
+					t.Errorf("Image size didn't match expected: got %d want %d", img.Bounds().Dx(), tt.opts.Size)
+				}
+			case FormatSVG:
+				if !strings.HasPrefix(string(data), "<svg") {
+					t.Errorf("Response is not svg: %s", data)
+				}
+			}
+		})
+	}
+}
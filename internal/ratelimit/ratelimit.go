@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit, expected format N/s, N/m or N/h")
+
+// Ограничение в виде корзины токенов: Burst токенов, пополняемых со скоростью Rate в секунду
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Разбирает ограничение вида "100/m": до 100 запросов подряд, корзина полностью пополняется за минуту.
+// Пустая строка означает отсутствие ограничения
+func ParseLimit(s string) (Limit, bool, error) {
+	if s == "" {
+		return Limit{}, false, nil
+	}
+
+	n, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, false, ErrInvalidLimit
+	}
+
+	burst, err := strconv.Atoi(n)
+	if err != nil || burst <= 0 {
+		return Limit{}, false, ErrInvalidLimit
+	}
+
+	var d time.Duration
+	switch period {
+	case "s":
+		d = time.Second
+	case "m":
+		d = time.Minute
+	case "h":
+		d = time.Hour
+	default:
+		return Limit{}, false, ErrInvalidLimit
+	}
+
+	return Limit{Rate: float64(burst) / d.Seconds(), Burst: burst}, true, nil
+}
+
+// Результат попытки взять токен
+type Result struct {
+	Allowed   bool
+	Remaining int
+	//Время до появления следующего токена, если запрос отклонен
+	RetryAfter time.Duration
+	//Время до полного пополнения корзины
+	Reset time.Duration
+}
+
+// Хранилище состояния корзин
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Вычисляет результат по текущему числу токенов после пополнения
+func result(tokens float64, allowed bool, limit Limit) Result {
+	res := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s < 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Хранилище корзин в памяти процесса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// Фабричный метод для создания хранилища в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(b.tokens, allowed, limit), nil
+}
+
+// Периодически удаляет корзины, к которым не обращались дольше idle, чтобы память не росла с числом клиентов.
+// idle должен быть не меньше времени полного пополнения корзины
+func (s *MemoryStore) Cleanup(ctx context.Context, interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			now := s.now()
+			for key, b := range s.buckets {
+				if now.Sub(b.last) > idle {
+					delete(s.buckets, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
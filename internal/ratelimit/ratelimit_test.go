@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec      string
+		expected  Limit
+		enabled   bool
+		expectErr bool
+	}{
+		{spec: "", enabled: false},
+		{spec: "10/s", expected: Limit{Rate: 10, Burst: 10}, enabled: true},
+		{spec: "60/m", expected: Limit{Rate: 1, Burst: 60}, enabled: true},
+		{spec: "10/d", expectErr: true},
+		{spec: "-1/s", expectErr: true},
+		{spec: "ten", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, ok, err := ParseLimit(tt.spec)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok != tt.enabled || got != tt.expected {
+				t.Errorf("Limit didn't match expected: got %+v %v want %+v %v", got, ok, tt.expected, tt.enabled)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i, expected := range []bool{true, true, false} {
+		res, err := s.Take(ctx, "client", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != expected {
+			t.Errorf("Request %d: allowed didn't match expected: got %v want %v", i, res.Allowed, expected)
+		}
+	}
+
+	res, _ := s.Take(ctx, "client", limit)
+	if res.RetryAfter != time.Second {
+		t.Errorf("RetryAfter didn't match expected: got %v want %v", res.RetryAfter, time.Second)
+	}
+
+	//Другой клиент ограничивается отдельно
+	if res, _ := s.Take(ctx, "other", limit); !res.Allowed {
+		t.Errorf("Expected other client to be allowed")
+	}
+
+	//За секунду появляется один токен
+	now = now.Add(time.Second)
+	res, _ = s.Take(ctx, "client", limit)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Expected request after refill to be allowed, got %+v", res)
+	}
+}
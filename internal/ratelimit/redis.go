@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Скрипт атомарно пополняет корзину и берет из нее токен.
+// Состояние хранится в хэше: tokens - число токенов, ts - время последнего обращения в миллисекундах
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, tostring(tokens)}
+`)
+
+// Хранилище корзин в Redis или совместимом сервере, позволяет делить ограничения между экземплярами сервиса
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// Фабричный метод для создания хранилища в Redis по адресу вида redis://host:6379/0
+func NewRedisStore(addr string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: redis.NewClient(opts), prefix: "ratelimit:"}, nil
+}
+
+// Проверяет доступность Redis
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now().UnixMilli()
+
+	values, err := takeScript.Run(ctx, s.client, []string{s.prefix + key}, limit.Rate, limit.Burst, now).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	tokens, err := parseFloat(values[1])
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result(tokens, allowed == 1, limit), nil
+}
+
+// Lua-скрипт возвращает дробное число строкой, так как числа Lua приводятся к целым в ответе Redis
+func parseFloat(v any) (float64, error) {
+	str, _ := v.(string)
+	return strconv.ParseFloat(str, 64)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	model "github.com/IgorGreusunset/shortener/internal/app"
@@ -42,10 +44,101 @@ func NewDatabase(dbConfig string) (*DBRepositoryAdapter, error) {
 		return nil, err
 	}
 
-	//Создаем индекс для полной ссылки
-	_, err = tx.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS original_url ON shorten_urls (original_url)")
+	//Добавляем колонки, появившиеся после создания таблицы
+	_, err = tx.ExecContext(ctx, `ALTER TABLE shorten_urls
+		ADD COLUMN IF NOT EXISTS user_id VARCHAR(50),
+		ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE,
+		ADD COLUMN IF NOT EXISTS redirect_code INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS clicks INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS tenant VARCHAR(50) NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS max_clicks INTEGER NOT NULL DEFAULT 0`)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	//Таблица истории изменений полных ссылок
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS url_revisions (
+		id SERIAL PRIMARY KEY,
+		short_url VARCHAR(50),
+		old_url TEXT,
+		new_url TEXT,
+		actor VARCHAR(50),
+		changed TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `ALTER TABLE url_revisions ADD COLUMN IF NOT EXISTS tenant VARCHAR(50) NOT NULL DEFAULT ''`)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	//До появления тенантов короткая ссылка не была уникальной в базе. Повторы не удаляем автоматически,
+	//чтобы не потерять ссылки, а останавливаем запуск со списком повторов для ручного разбора
+	if err = checkDuplicateShortURLs(ctx, tx); err != nil {
+		logger.Log.Errorw("Error during index creation", "error", err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	//Полная и короткая ссылки уникальны в пределах тенанта, полная - только среди ссылок, выдаваемых повторно (model.URL.Shareable).
+	//Индекс по одной полной ссылке остался от версий без тенантов, индекс по всем ссылкам тенанта - от версий без лимита переходов
+	for _, query := range []string{
+		"DROP INDEX IF EXISTS original_url",
+		"DROP INDEX IF EXISTS shorten_urls_tenant_original_url",
+		"CREATE UNIQUE INDEX IF NOT EXISTS " + originalURLIndex + " ON shorten_urls (tenant, original_url) WHERE " + shareableCondition,
+		"CREATE UNIQUE INDEX IF NOT EXISTS shorten_urls_tenant_short_url ON shorten_urls (tenant, short_url)",
+	} {
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			logger.Log.Errorw("Error during index creation", "query", query, "error", err)
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	//Индекс для выборки ссылок пользователя
+	_, err = tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS shorten_urls_user_id ON shorten_urls (user_id)")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	//Таблица ключей API, хранится только хеш ключа
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS api_keys (
+		id VARCHAR(32) PRIMARY KEY,
+		user_id VARCHAR(50) NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		prefix VARCHAR(32) NOT NULL,
+		scopes TEXT NOT NULL,
+		created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		revoked TIMESTAMP
+	)`)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS api_keys_user_id ON api_keys (user_id)")
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	//Дневные счетчики созданных пользователями ссылок. Хранятся отдельно от ссылок, чтобы удаление не возвращало квоту
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS user_usage (
+		tenant VARCHAR(50) NOT NULL DEFAULT '',
+		user_id VARCHAR(50) NOT NULL,
+		day DATE NOT NULL,
+		created INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (tenant, user_id, day)
+	)`)
 	if err != nil {
-		logger.Log.Debugln("Error during index creation: %v", err)
 		tx.Rollback()
 		return nil, err
 	}
@@ -54,47 +147,80 @@ func NewDatabase(dbConfig string) (*DBRepositoryAdapter, error) {
 }
 
 func (db *DBRepositoryAdapter) Create(ctx context.Context, record *model.URL) error {
+	if record.Tenant == "" {
+		record.Tenant = tenantID(ctx)
+	}
+	if record.UUID > 0 {
+		return db.createWithUUID(ctx, record)
+	}
 
-	_, err := db.DB.ExecContext(ctx,
-		`INSERT INTO shorten_urls(short_url, original_url, created) VALUES ($1, $2, $3);`,
+	_, err := execContext(ctx, db.DB,
+		insertURL,
 		record.ID,
 		record.FullURL,
-		time.Now())
+		record.UserID,
+		createdAt(record.Created),
+		record.Disabled,
+		record.RedirectCode,
+		record.Clicks,
+		record.Tenant,
+		record.PasswordHash,
+		record.MaxClicks)
 
 	if err != nil {
 
 		//Проверяем ошибку из БД, если ошибка из-за конфликта индекса - оборачиваем, для передачи существующего ID
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == pgerrcode.UniqueViolation {
-				ue := db.NewURLExistsError(record.FullURL, err)
-				return ue
-			}
+		if isOriginalURLConflict(err) {
+			ue := db.NewURLExistsError(record.Tenant, record.FullURL, err)
+			return ue
 		}
-		logger.Log.Debugln(err)
+		logger.FromContext(ctx).Debugln(err)
 		return err
 	}
 	return nil
 }
 
-func (db *DBRepositoryAdapter) GetByID(id string) (model.URL, bool) {
-	var (
-		UUID    int
-		ID      string
-		FullURL string
-	)
+// Создает запись с заданным UUID, например при загрузке выгрузки, и сдвигает последовательность uuid,
+// чтобы следующие записи не получили занятый UUID
+func (db *DBRepositoryAdapter) createWithUUID(ctx context.Context, record *model.URL) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = execContext(ctx, tx, insertURLWithUUID,
+		record.UUID, record.ID, record.FullURL, record.UserID, createdAt(record.Created), record.Disabled,
+		record.RedirectCode, record.Clicks, record.Tenant, record.PasswordHash, record.MaxClicks)
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case isOriginalURLConflict(err):
+			return db.NewURLExistsError(record.Tenant, record.FullURL, err)
+		case isUUIDConflict(err):
+			return ErrUUIDExists
+		}
+		return err
+	}
+
+	_, err = execContext(ctx, tx, `SELECT setval(pg_get_serial_sequence('shorten_urls', 'uuid'), (SELECT max(uuid) FROM shorten_urls));`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
 
-	row := db.DB.QueryRow(`SELECT uuid, short_url, original_url FROM shorten_urls WHERE short_url = $1;`, id)
+func (db *DBRepositoryAdapter) GetByID(ctx context.Context, id string) (model.URL, bool) {
+	row := queryRowContext(ctx, db.DB, `SELECT `+urlColumns+` FROM shorten_urls WHERE tenant = $1 AND short_url = $2;`, tenantID(ctx), id)
 
-	err := row.Scan(&UUID, &ID, &FullURL)
+	result, err := scanURL(row)
 	if err != nil {
-		logger.Log.Errorln(err)
+		logger.FromContext(ctx).Errorln(err)
 		return model.URL{}, false
 	}
 
-	result := model.NewURL(ID, FullURL)
-	result.UUID = UUID
-	return *result, true
+	return result, true
 }
 
 func (db *DBRepositoryAdapter) Ping() error {
@@ -102,16 +228,19 @@ func (db *DBRepositoryAdapter) Ping() error {
 }
 
 func (db *DBRepositoryAdapter) CreateBatch(ctx context.Context, urls []model.URL) error {
-	tx, err := db.DB.Begin()
+	tx, err := db.DB.BeginTx(ctx, nil)
 
 	if err != nil {
 		return err
 	}
 
 	for _, u := range urls {
-		_, err = tx.ExecContext(ctx,
-			`INSERT INTO shorten_urls(short_url, original_url, created) VALUES ($1, $2, $3);`,
-			u.ID, u.FullURL, time.Now())
+		if u.Tenant == "" {
+			u.Tenant = tenantID(ctx)
+		}
+		_, err = execContext(ctx, tx,
+			insertURL,
+			u.ID, u.FullURL, u.UserID, createdAt(u.Created), u.Disabled, u.RedirectCode, u.Clicks, u.Tenant, u.PasswordHash, u.MaxClicks)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -121,6 +250,485 @@ func (db *DBRepositoryAdapter) CreateBatch(ctx context.Context, urls []model.URL
 	return tx.Commit()
 }
 
+func (db *DBRepositoryAdapter) Walk(ctx context.Context, fn func(model.URL) error) error {
+	rows, err := queryContext(ctx, db.DB, `SELECT `+urlColumns+` FROM shorten_urls ORDER BY uuid;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (db *DBRepositoryAdapter) List(ctx context.Context, query string) ([]model.URL, error) {
+	rows, err := queryContext(ctx, db.DB,
+		`SELECT `+urlColumns+` FROM shorten_urls WHERE tenant = $1 AND strpos(original_url, $2) > 0 ORDER BY uuid;`, tenantID(ctx), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.URL
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	return result, rows.Err()
+}
+
+func (db *DBRepositoryAdapter) ListByUser(ctx context.Context, userID string) ([]model.URL, error) {
+	rows, err := queryContext(ctx, db.DB,
+		`SELECT `+urlColumns+` FROM shorten_urls WHERE tenant = $1 AND user_id = $2 ORDER BY uuid;`, tenantID(ctx), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.URL
+	for rows.Next() {
+		u, err := scanURL(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, u)
+	}
+
+	return result, rows.Err()
+}
+
+func (db *DBRepositoryAdapter) Update(ctx context.Context, record *model.URL) error {
+	row := queryRowContext(ctx, db.DB,
+		`UPDATE shorten_urls SET original_url = $3, disabled = $4 WHERE tenant = $1 AND short_url = $2 RETURNING `+urlColumns+`;`,
+		tenantID(ctx), record.ID, record.FullURL, record.Disabled)
+
+	updated, err := scanURL(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if isOriginalURLConflict(err) {
+			return db.NewURLExistsError(tenantID(ctx), record.FullURL, err)
+		}
+		return err
+	}
+
+	*record = updated
+	return nil
+}
+
+func (db *DBRepositoryAdapter) Delete(ctx context.Context, id string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	t := tenantID(ctx)
+	res, err := execContext(ctx, tx, `DELETE FROM shorten_urls WHERE tenant = $1 AND short_url = $2;`, t, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := execContext(ctx, tx, `DELETE FROM url_revisions WHERE tenant = $1 AND short_url = $2;`, t, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Меняет полную ссылку и пишет изменение в историю в одной транзакции.
+// Строка блокируется, чтобы параллельные изменения не потеряли ревизию
+func (db *DBRepositoryAdapter) Retarget(ctx context.Context, id, newURL, actor string) (model.URL, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return model.URL{}, err
+	}
+	defer tx.Rollback()
+
+	existing, err := scanURL(queryRowContext(ctx, tx,
+		`SELECT `+urlColumns+` FROM shorten_urls WHERE tenant = $1 AND short_url = $2 FOR UPDATE;`, tenantID(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.URL{}, ErrNotFound
+	}
+	if err != nil {
+		return model.URL{}, err
+	}
+	if existing.FullURL == newURL {
+		return existing, nil
+	}
+
+	_, err = execContext(ctx, tx, `UPDATE shorten_urls SET original_url = $3 WHERE tenant = $1 AND short_url = $2;`, existing.Tenant, id, newURL)
+	if err != nil {
+		if isOriginalURLConflict(err) {
+			tx.Rollback()
+			return model.URL{}, db.NewURLExistsError(existing.Tenant, newURL, err)
+		}
+		return model.URL{}, err
+	}
+
+	_, err = execContext(ctx, tx,
+		`INSERT INTO url_revisions(tenant, short_url, old_url, new_url, actor, changed) VALUES ($1, $2, $3, $4, $5, $6);`,
+		existing.Tenant, id, existing.FullURL, newURL, actor, time.Now())
+	if err != nil {
+		return model.URL{}, err
+	}
+
+	existing.FullURL = newURL
+	return existing, tx.Commit()
+}
+
+func (db *DBRepositoryAdapter) History(ctx context.Context, id string) ([]model.Revision, error) {
+	if _, ok := db.GetByID(ctx, id); !ok {
+		return nil, ErrNotFound
+	}
+
+	rows, err := queryContext(ctx, db.DB,
+		`SELECT tenant, short_url, old_url, new_url, actor, changed FROM url_revisions WHERE tenant = $1 AND short_url = $2 ORDER BY id;`, tenantID(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []model.Revision{}
+	for rows.Next() {
+		var rev model.Revision
+		if err := rows.Scan(&rev.Tenant, &rev.ID, &rev.OldURL, &rev.NewURL, &rev.Actor, &rev.Changed); err != nil {
+			return nil, err
+		}
+		result = append(result, rev)
+	}
+
+	return result, rows.Err()
+}
+
+func (db *DBRepositoryAdapter) RegisterClick(ctx context.Context, id string) error {
+	//Условие на лимит проверяется под блокировкой строки, поэтому параллельные переходы не превышают max_clicks
+	t := tenantID(ctx)
+	res, err := execContext(ctx, db.DB,
+		`UPDATE shorten_urls SET clicks = clicks + 1 WHERE tenant = $1 AND short_url = $2 AND (max_clicks = 0 OR clicks < max_clicks);`, t, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	//Строка не обновлена: ссылки нет или лимит переходов исчерпан
+	var exists bool
+	err = queryRowContext(ctx, db.DB, `SELECT EXISTS(SELECT 1 FROM shorten_urls WHERE tenant = $1 AND short_url = $2);`, t, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrClicksExhausted
+	}
+	return ErrNotFound
+}
+
+func (db *DBRepositoryAdapter) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	_, err := execContext(ctx, db.DB,
+		`INSERT INTO api_keys(id, user_id, name, key_hash, prefix, scopes, created) VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		key.ID, key.UserID, key.Name, key.Hash, key.Prefix, strings.Join(key.Scopes, ","), createdAt(key.Created))
+	return err
+}
+
+func (db *DBRepositoryAdapter) GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	key, err := scanAPIKey(queryRowContext(ctx, db.DB,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1;`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.APIKey{}, ErrNotFound
+	}
+	return key, err
+}
+
+func (db *DBRepositoryAdapter) ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	rows, err := queryContext(ctx, db.DB,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created;`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, key)
+	}
+
+	return result, rows.Err()
+}
+
+// Отзывает ключ пользователя. Повторный отзыв не меняет время отзыва
+func (db *DBRepositoryAdapter) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	res, err := execContext(ctx, db.DB,
+		`UPDATE api_keys SET revoked = COALESCE(revoked, $3) WHERE id = $1 AND user_id = $2;`, id, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (db *DBRepositoryAdapter) Count(ctx context.Context) (int, error) {
+	var n int
+	err := queryRowContext(ctx, db.DB, `SELECT count(*) FROM shorten_urls WHERE tenant = $1;`, tenantID(ctx)).Scan(&n)
+	return n, err
+}
+
+func (db *DBRepositoryAdapter) Usage(ctx context.Context, userID string, day time.Time) (model.Usage, error) {
+	var usage model.Usage
+	err := queryRowContext(ctx, db.DB,
+		`SELECT (SELECT count(*) FROM shorten_urls WHERE tenant = $1 AND user_id = $2 AND NOT disabled),
+			COALESCE((SELECT created FROM user_usage WHERE tenant = $1 AND user_id = $2 AND day = $3::date), 0);`,
+		tenantID(ctx), userID, usageDay(day)).Scan(&usage.Active, &usage.Created)
+	return usage, err
+}
+
+// Создает ссылки с проверкой квот в одной транзакции. Квоты проверяются под блокировками, которые держатся
+// до конца транзакции, поэтому параллельные запросы тенанта и пользователя не превышают квоты
+func (db *DBRepositoryAdapter) CreateWithQuota(ctx context.Context, urls []model.URL, quota model.Quota) error {
+	t := tenantID(ctx)
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = reserveQuota(ctx, tx, t, len(urls), quota); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i := range urls {
+		u := &urls[i]
+		if u.Tenant == "" {
+			u.Tenant = t
+		}
+		_, err = execContext(ctx, tx, insertURL,
+			u.ID, u.FullURL, u.UserID, createdAt(u.Created), u.Disabled, u.RedirectCode, u.Clicks, u.Tenant, u.PasswordHash, u.MaxClicks)
+		if err != nil {
+			//Откат транзакции отменяет и увеличение дневного счетчика
+			tx.Rollback()
+			if isOriginalURLConflict(err) {
+				return db.NewURLExistsError(u.Tenant, u.FullURL, err)
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Проверяет квоты перед созданием n ссылок и увеличивает дневной счетчик пользователя
+func reserveQuota(ctx context.Context, tx *sql.Tx, tenantID string, n int, quota model.Quota) error {
+	if quota.MaxLinks > 0 {
+		//Ссылки тенанта создают разные пользователи, поэтому подсчет сериализуем рекомендательной блокировкой тенанта
+		if _, err := execContext(ctx, tx, `SELECT pg_advisory_xact_lock(hashtext('shorten_urls:' || $1));`, tenantID); err != nil {
+			return err
+		}
+		var count int
+		if err := queryRowContext(ctx, tx, `SELECT count(*) FROM shorten_urls WHERE tenant = $1;`, tenantID).Scan(&count); err != nil {
+			return err
+		}
+		if count+n > quota.MaxLinks {
+			return ErrTenantQuota
+		}
+	}
+
+	if quota.UserID == "" {
+		return nil
+	}
+
+	//Вставка или обновление блокирует строку счетчика, параллельные запросы пользователя ждут конца транзакции
+	day := usageDay(quota.Day)
+	var created, active int
+	err := queryRowContext(ctx, tx,
+		`INSERT INTO user_usage(tenant, user_id, day) VALUES ($1, $2, $3::date)
+			ON CONFLICT (tenant, user_id, day) DO UPDATE SET created = user_usage.created RETURNING created;`,
+		tenantID, quota.UserID, day).Scan(&created)
+	if err != nil {
+		return err
+	}
+
+	if quota.ActiveLimit > 0 {
+		err = queryRowContext(ctx, tx,
+			`SELECT count(*) FROM shorten_urls WHERE tenant = $1 AND user_id = $2 AND NOT disabled;`, tenantID, quota.UserID).Scan(&active)
+		if err != nil {
+			return err
+		}
+		if active+n > quota.ActiveLimit {
+			return ErrActiveQuota
+		}
+	}
+	if quota.DailyLimit > 0 && created+n > quota.DailyLimit {
+		return ErrDailyQuota
+	}
+
+	_, err = execContext(ctx, tx,
+		`UPDATE user_usage SET created = created + $4 WHERE tenant = $1 AND user_id = $2 AND day = $3::date;`,
+		tenantID, quota.UserID, day, n)
+	return err
+}
+
+// Сутки дневной квоты в формате колонки user_usage.day
+func usageDay(day time.Time) string {
+	return day.UTC().Format(time.DateOnly)
+}
+
+// Ошибка миграции при повторах короткой ссылки в пределах тенанта
+var ErrDuplicateShortURLs = errors.New("duplicate short urls, remove them before upgrading")
+
+// Проверяет, что короткие ссылки не повторяются в пределах тенанта, иначе уникальный индекс не создать.
+// В ошибке перечисляет до десяти повторов
+func checkDuplicateShortURLs(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT tenant, COALESCE(short_url, ''), count(*) FROM shorten_urls GROUP BY tenant, short_url HAVING count(*) > 1 ORDER BY tenant, short_url LIMIT 10;`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var duplicates []string
+	for rows.Next() {
+		var (
+			tenantID, shortURL string
+			n                  int
+		)
+		if err := rows.Scan(&tenantID, &shortURL, &n); err != nil {
+			return err
+		}
+		duplicates = append(duplicates, fmt.Sprintf("%s x%d", urlKey(tenantID, shortURL), n))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateShortURLs, strings.Join(duplicates, ", "))
+	}
+	return nil
+}
+
+// Имя уникального индекса полной ссылки в пределах тенанта
+const originalURLIndex = "shorten_urls_tenant_original_url_shared"
+
+// Условие на ссылки, которые выдаются повторно на ту же полную ссылку, см. model.URL.Shareable
+const shareableCondition = "max_clicks = 0 AND password_hash = ''"
+
+// Проверяет, что ошибка вызвана повтором полной ссылки, а не другим уникальным индексом
+func isOriginalURLConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == originalURLIndex
+}
+
+// Проверяет, что ошибка вызвана повтором UUID, первичного ключа таблицы shorten_urls
+func isUUIDConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "shorten_urls_pkey"
+}
+
+// Колонки таблицы api_keys в порядке, ожидаемом scanAPIKey
+const apiKeyColumns = "id, user_id, name, key_hash, prefix, scopes, created, revoked"
+
+// Считывает строку таблицы api_keys в модель. Права хранятся строкой через запятую
+func scanAPIKey(row scanner) (model.APIKey, error) {
+	var (
+		key     model.APIKey
+		scopes  string
+		created sql.NullTime
+		revoked sql.NullTime
+	)
+
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Hash, &key.Prefix, &scopes, &created, &revoked); err != nil {
+		return model.APIKey{}, err
+	}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	key.Created = created.Time
+	if revoked.Valid {
+		key.Revoked = &revoked.Time
+	}
+
+	return key, nil
+}
+
+// Запрос для вставки записи с заданным UUID в таблицу shorten_urls
+const insertURLWithUUID = `INSERT INTO shorten_urls(uuid, short_url, original_url, user_id, created, disabled, redirect_code, clicks, tenant, password_hash, max_clicks) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);`
+
+// Запрос для вставки записи в таблицу shorten_urls
+const insertURL = `INSERT INTO shorten_urls(short_url, original_url, user_id, created, disabled, redirect_code, clicks, tenant, password_hash, max_clicks) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+
+// Колонки таблицы shorten_urls в порядке, ожидаемом scanURL
+const urlColumns = "uuid, short_url, original_url, user_id, created, disabled, redirect_code, clicks, tenant, password_hash, max_clicks"
+
+// Общий интерфейс для sql.Row и sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// Считывает строку таблицы shorten_urls в модель
+func scanURL(row scanner) (model.URL, error) {
+	var (
+		u       model.URL
+		userID  sql.NullString
+		created sql.NullTime
+	)
+
+	if err := row.Scan(&u.UUID, &u.ID, &u.FullURL, &userID, &created, &u.Disabled, &u.RedirectCode, &u.Clicks, &u.Tenant, &u.PasswordHash, &u.MaxClicks); err != nil {
+		return model.URL{}, err
+	}
+	u.UserID = userID.String
+	u.Created = created.Time
+
+	return u, nil
+}
+
+// Возвращает время создания записи, подставляя текущее, если оно не задано
+func createdAt(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
 // Обертка для ошибки при создании записи с существующим original_url. Позволяет передать дальше short_url из базы
 type URLExistsError struct {
 	ShortURL string
@@ -131,9 +739,9 @@ func (uee *URLExistsError) Error() string {
 	return uee.Er
 }
 
-func (db *DBRepositoryAdapter) NewURLExistsError(originalURL string, e error) *URLExistsError {
+func (db *DBRepositoryAdapter) NewURLExistsError(tenantID, originalURL string, e error) *URLExistsError {
 	var ID string
-	row := db.DB.QueryRow(`SELECT short_url FROM shorten_urls WHERE original_url = $1;`, originalURL)
+	row := db.DB.QueryRow(`SELECT short_url FROM shorten_urls WHERE tenant = $1 AND original_url = $2 AND `+shareableCondition+`;`, tenantID, originalURL)
 	row.Scan(&ID)
 	return &URLExistsError{ShortURL: ID, Er: "Original URL already in DB"}
 }
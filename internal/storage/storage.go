@@ -4,34 +4,94 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+)
+
+// Ошибка при обращении к отсутствующей записи
+var ErrNotFound = errors.New("url not found")
+
+// Ошибка при переходе по ссылке, исчерпавшей допустимое число переходов
+var ErrClicksExhausted = errors.New("url click limit exhausted")
+
+// Ошибка при создании записи с UUID, который уже занят другой записью
+var ErrUUIDExists = errors.New("url uuid already exists")
+
+// Ошибки CreateWithQuota при исчерпании квот тенанта, активных ссылок и дневной квоты пользователя
+var (
+	ErrTenantQuota = errors.New("tenant link quota exceeded")
+	ErrActiveQuota = errors.New("active link quota exceeded")
+	ErrDailyQuota  = errors.New("daily link quota exceeded")
 )
 
 type Storage struct {
-	db   map[string]model.URL
-	file *os.File
-	mu   sync.RWMutex
-	scan *bufio.Scanner
+	db       map[string]model.URL
+	file     *os.File
+	mu       sync.RWMutex
+	scan     *bufio.Scanner
+	lastUUID int
+	history  map[string][]model.Revision
+	keys     map[string]model.APIKey
+	usage    map[string]dailyUsage
+	//Есть переходы, еще не сохраненные в файл
+	dirty bool
 }
 
 // Фабричный метод создания нового экземпляра хранилища
 func NewStorage(db map[string]model.URL) *Storage {
-	return &Storage{db: db}
+	return &Storage{db: db, history: map[string][]model.Revision{}, keys: map[string]model.APIKey{}, usage: map[string]dailyUsage{}}
 }
 
 func (s *Storage) SetFile(f *os.File) {
 	s.file = f
 }
 
+// Хранилище ссылок. Операции с короткими ID выполняются в пространстве тенанта из контекста
+// (tenant.FromContext), Create сохраняет запись в тенант, указанный в ней, или в тенант из контекста.
+// Create сохраняет заданный в записи UUID и возвращает ErrUUIDExists, если он занят.
+// Walk обходит записи всех тенантов
 type Repository interface {
 	Create(ctx context.Context, record *model.URL) error
-	GetByID(id string) (model.URL, bool)
+	GetByID(ctx context.Context, id string) (model.URL, bool)
 	Ping() error
 	CreateBatch(ctx context.Context, urls []model.URL) error
+	Walk(ctx context.Context, fn func(model.URL) error) error
+	List(ctx context.Context, query string) ([]model.URL, error)
+	ListByUser(ctx context.Context, userID string) ([]model.URL, error)
+	Update(ctx context.Context, record *model.URL) error
+	Delete(ctx context.Context, id string) error
+	Retarget(ctx context.Context, id, newURL, actor string) (model.URL, error)
+	History(ctx context.Context, id string) ([]model.Revision, error)
+	RegisterClick(ctx context.Context, id string) error
+	CreateAPIKey(ctx context.Context, key *model.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string) error
+	Count(ctx context.Context) (int, error)
+	Usage(ctx context.Context, userID string, day time.Time) (model.Usage, error)
+	CreateWithQuota(ctx context.Context, urls []model.URL, quota model.Quota) error
+}
+
+// Ключ записи в карте хранилища: короткие ID уникальны только в пределах тенанта
+func urlKey(tenantID, id string) string {
+	if tenantID == "" {
+		return id
+	}
+	return tenantID + "/" + id
+}
+
+// ID тенанта из контекста запроса
+func tenantID(ctx context.Context) string {
+	return tenant.FromContext(ctx).ID
 }
 
 // Метод для создания новой записи в хранилище
@@ -39,8 +99,34 @@ func (s *Storage) Create(ctx context.Context, record *model.URL) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.db[record.ID] = *record
-	record.UUID = len(s.db)
+	return s.create(ctx, record)
+}
+
+// Создает запись и дописывает ее в файл. Вызывается под блокировкой
+func (s *Storage) create(ctx context.Context, record *model.URL) error {
+	if record.Tenant == "" {
+		record.Tenant = tenantID(ctx)
+	}
+
+	if u, ok := s.duplicate(*record); ok {
+		return &URLExistsError{ShortURL: u.ID, Er: "Original URL already in DB"}
+	}
+
+	//Заданный UUID сохраняется, например при загрузке выгрузки, иначе выдается следующий
+	if record.UUID > 0 {
+		for _, u := range s.db {
+			if u.UUID == record.UUID {
+				return ErrUUIDExists
+			}
+		}
+		if record.UUID > s.lastUUID {
+			s.lastUUID = record.UUID
+		}
+	} else {
+		s.lastUUID++
+		record.UUID = s.lastUUID
+	}
+	s.db[urlKey(record.Tenant, record.ID)] = *record
 
 	if s.file != nil {
 		name := s.file.Name()
@@ -52,17 +138,40 @@ func (s *Storage) Create(ctx context.Context, record *model.URL) error {
 	return nil
 }
 
+// Ищет другую ссылку тенанта на ту же полную ссылку, которую можно выдать вместо record.
+// Повторно выдаются только ссылки без ограничений, см. model.URL.Shareable. Вызывается под блокировкой
+func (s *Storage) duplicate(record model.URL) (model.URL, bool) {
+	if !record.Shareable() {
+		return model.URL{}, false
+	}
+	for _, u := range s.db {
+		if u.Tenant == record.Tenant && u.FullURL == record.FullURL && u.ID != record.ID && u.Shareable() {
+			return u, true
+		}
+	}
+	return model.URL{}, false
+}
+
 // Метода для получения записи из хранилища
-func (s *Storage) GetByID(id string) (model.URL, bool) {
+func (s *Storage) GetByID(ctx context.Context, id string) (model.URL, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	url, ok := s.db[id]
+	url, ok := s.db[urlKey(tenantID(ctx), id)]
 	return url, ok
 }
 
+// Проверяет, что файл хранилища доступен для записи. Без файла хранилище работает только в памяти
 func (s *Storage) Ping() error {
-	return nil
+	if s.file == nil {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.file.Name(), os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 func (s *Storage) FillFromFile(file *os.File) error {
@@ -70,14 +179,88 @@ func (s *Storage) FillFromFile(file *os.File) error {
 	s.scan = bufio.NewScanner(file)
 
 	for s.scan.Scan() {
+		//Поля с omitempty отсутствуют в строке, поэтому не переносим их из предыдущей записи
+		*url = model.URL{}
 		err := json.Unmarshal(s.scan.Bytes(), url)
 		if err != nil {
 			return err
 		}
-		s.db[url.ID] = *url
+		s.db[urlKey(url.Tenant, url.ID)] = *url
+		if url.UUID > s.lastUUID {
+			s.lastUUID = url.UUID
+		}
 	}
 
-	return nil
+	if err := s.fillHistory(historyPath(file.Name())); err != nil {
+		return err
+	}
+	if err := s.fillUsage(usagePath(file.Name())); err != nil {
+		return err
+	}
+	return s.fillKeys(keysPath(file.Name()))
+}
+
+// Загружает историю изменений ссылок из файла рядом с основным, если он есть
+func (s *Storage) fillHistory(name string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		var rev model.Revision
+		if err := json.Unmarshal(scan.Bytes(), &rev); err != nil {
+			return err
+		}
+		key := urlKey(rev.Tenant, rev.ID)
+		s.history[key] = append(s.history[key], rev)
+	}
+
+	return scan.Err()
+}
+
+// Загружает ключи API из файла рядом с основным, если он есть.
+// Более поздняя строка с тем же ID заменяет предыдущую
+func (s *Storage) fillKeys(name string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		var key model.APIKey
+		if err := json.Unmarshal(scan.Bytes(), &key); err != nil {
+			return err
+		}
+		s.keys[key.ID] = key
+	}
+
+	return scan.Err()
+}
+
+// Путь к файлу ключей API для файла хранилища
+func keysPath(name string) string {
+	return name + ".keys"
+}
+
+// Путь к файлу истории изменений для файла хранилища
+func historyPath(name string) string {
+	return name + ".history"
+}
+
+// Путь к файлу дневных счетчиков пользователей для файла хранилища
+func usagePath(name string) string {
+	return name + ".usage"
 }
 
 func saveToFile(url model.URL, file string) error {
@@ -107,3 +290,492 @@ func (s *Storage) CreateBatch(ctx context.Context, urls []model.URL) error {
 	}
 	return nil
 }
+
+// Метод для последовательного обхода всех записей хранилища в порядке UUID
+func (s *Storage) Walk(ctx context.Context, fn func(model.URL) error) error {
+	s.mu.RLock()
+	urls := make([]model.URL, 0, len(s.db))
+	for _, u := range s.db {
+		urls = append(urls, u)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(urls, func(i, j int) bool { return urls[i].UUID < urls[j].UUID })
+
+	for _, u := range urls {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Метод для поиска записей тенанта по подстроке полной ссылки, пустой запрос возвращает все записи
+func (s *Storage) List(ctx context.Context, query string) ([]model.URL, error) {
+	t := tenantID(ctx)
+	var result []model.URL
+	err := s.Walk(ctx, func(u model.URL) error {
+		if u.Tenant == t && strings.Contains(u.FullURL, query) {
+			result = append(result, u)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Метод для получения ссылок пользователя в тенанте в порядке создания
+func (s *Storage) ListByUser(ctx context.Context, userID string) ([]model.URL, error) {
+	t := tenantID(ctx)
+	var result []model.URL
+	err := s.Walk(ctx, func(u model.URL) error {
+		if u.Tenant == t && u.UserID == userID {
+			result = append(result, u)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Метод для изменения полной ссылки и статуса существующей записи
+func (s *Storage) Update(ctx context.Context, record *model.URL) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := urlKey(tenantID(ctx), record.ID)
+	existing, ok := s.db[key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	existing.FullURL = record.FullURL
+	existing.Disabled = record.Disabled
+	if u, ok := s.duplicate(existing); ok {
+		return &URLExistsError{ShortURL: u.ID, Er: "Original URL already in DB"}
+	}
+	s.db[key] = existing
+	*record = existing
+
+	if s.file != nil {
+		return s.rewriteFile()
+	}
+	return nil
+}
+
+// Метод для безвозвратного удаления записи
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := urlKey(tenantID(ctx), id)
+	if _, ok := s.db[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.db, key)
+	_, hasHistory := s.history[key]
+	delete(s.history, key)
+
+	if s.file == nil {
+		return nil
+	}
+	if err := s.rewriteFile(); err != nil {
+		return err
+	}
+	//История удаленной записи не должна вернуться при следующей загрузке файла
+	if hasHistory {
+		return s.rewriteHistory()
+	}
+	return nil
+}
+
+// Метод для смены полной ссылки с записью в историю изменений
+func (s *Storage) Retarget(ctx context.Context, id, newURL, actor string) (model.URL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := urlKey(tenantID(ctx), id)
+	existing, ok := s.db[key]
+	if !ok {
+		return model.URL{}, ErrNotFound
+	}
+	if existing.FullURL == newURL {
+		return existing, nil
+	}
+
+	//Новая ссылка не должна совпадать с полной ссылкой другой записи тенанта, которая выдается повторно
+	changed := existing
+	changed.FullURL = newURL
+	if u, ok := s.duplicate(changed); ok {
+		return model.URL{}, &URLExistsError{ShortURL: u.ID, Er: "Original URL already in DB"}
+	}
+
+	rev := model.Revision{
+		Tenant:  existing.Tenant,
+		ID:      id,
+		OldURL:  existing.FullURL,
+		NewURL:  newURL,
+		Actor:   actor,
+		Changed: time.Now(),
+	}
+
+	existing.FullURL = newURL
+	s.db[key] = existing
+	s.history[key] = append(s.history[key], rev)
+
+	if s.file != nil {
+		if err := s.rewriteFile(); err != nil {
+			return model.URL{}, err
+		}
+		if err := appendJSONLine(historyPath(s.file.Name()), rev); err != nil {
+			return model.URL{}, err
+		}
+	}
+	return existing, nil
+}
+
+// Метод для получения истории изменений полной ссылки
+func (s *Storage) History(ctx context.Context, id string) ([]model.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := urlKey(tenantID(ctx), id)
+	if _, ok := s.db[key]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]model.Revision{}, s.history[key]...), nil
+}
+
+// Дописывает значение строкой JSON в конец файла, создавая его при необходимости
+func appendJSONLine(name string, v any) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Перезаписывает файл хранилища текущим содержимым. Вызывается под блокировкой
+func (s *Storage) rewriteFile() error {
+	urls := make([]model.URL, 0, len(s.db))
+	for _, u := range s.db {
+		urls = append(urls, u)
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].UUID < urls[j].UUID })
+
+	if err := writeJSONLines(s.file.Name(), urls); err != nil {
+		return err
+	}
+	//Файл содержит текущие счетчики переходов всех записей
+	s.dirty = false
+	return nil
+}
+
+// Перезаписывает файл истории изменений текущим содержимым. Вызывается под блокировкой
+func (s *Storage) rewriteHistory() error {
+	var revs []model.Revision
+	for _, h := range s.history {
+		revs = append(revs, h...)
+	}
+	sort.SliceStable(revs, func(i, j int) bool { return revs[i].Changed.Before(revs[j].Changed) })
+
+	return writeJSONLines(historyPath(s.file.Name()), revs)
+}
+
+// Записывает значения строками JSON во временный файл и заменяет им файл name
+func writeJSONLines[T any](name string, values []T) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Метод для учета перехода по короткой ссылке. Для ссылки с исчерпанным лимитом переходов возвращает ErrClicksExhausted
+func (s *Storage) RegisterClick(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := urlKey(tenantID(ctx), id)
+	u, ok := s.db[key]
+	if !ok {
+		return ErrNotFound
+	}
+	//Проверка и увеличение счетчика выполняются под одной блокировкой, поэтому лимит не превышается
+	if u.MaxClicks > 0 && u.Clicks >= u.MaxClicks {
+		return ErrClicksExhausted
+	}
+	u.Clicks++
+	s.db[key] = u
+
+	//Счетчики сохраняются в файл периодически в FlushClicks, чтобы переход не писал в файл.
+	//Исчерпание лимита сохраняем сразу, иначе после перезапуска по ссылке можно будет перейти снова
+	if s.file == nil {
+		return nil
+	}
+	s.dirty = true
+	if u.MaxClicks > 0 && u.Clicks >= u.MaxClicks {
+		return s.rewriteFile()
+	}
+	return nil
+}
+
+// Сохраняет в файл счетчики переходов, если они изменились после последней записи файла
+func (s *Storage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil || !s.dirty {
+		return nil
+	}
+	return s.rewriteFile()
+}
+
+// Периодически сохраняет счетчики переходов в файл. При отмене контекста сохраняет их последний раз
+func (s *Storage) FlushClicks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				log.Printf("Error write to file: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Printf("Error write to file: %v", err)
+			}
+		}
+	}
+}
+
+// Метод для сохранения нового ключа API
+func (s *Storage) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = *key
+
+	if s.file != nil {
+		return appendJSONLine(keysPath(s.file.Name()), key)
+	}
+	return nil
+}
+
+// Метод для поиска ключа API по хешу
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return model.APIKey{}, ErrNotFound
+}
+
+// Метод для получения ключей API пользователя в порядке создания
+func (s *Storage) ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	s.mu.RLock()
+	var result []model.APIKey
+	for _, key := range s.keys {
+		if key.UserID == userID {
+			result = append(result, key)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.Before(result[j].Created) })
+	return result, nil
+}
+
+// Метод для отзыва ключа API пользователя. Повторный отзыв не меняет время отзыва
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || key.UserID != userID {
+		return ErrNotFound
+	}
+	if key.Revoked != nil {
+		return nil
+	}
+
+	now := time.Now()
+	key.Revoked = &now
+	s.keys[id] = key
+
+	//Дописываем актуальную версию ключа, при чтении файла более поздняя строка заменяет предыдущую
+	if s.file != nil {
+		return appendJSONLine(keysPath(s.file.Name()), key)
+	}
+	return nil
+}
+
+// Метод для подсчета ссылок тенанта
+func (s *Storage) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.count(tenantID(ctx)), nil
+}
+
+// Метод для подсчета использования пользователем в тенанте: активными считаются не отключенные ссылки,
+// созданными - ссылки, созданные за сутки day, включая удаленные
+func (s *Storage) Usage(ctx context.Context, userID string, day time.Time) (model.Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := tenantID(ctx)
+	usage := model.Usage{Active: s.active(t, userID)}
+	if u, ok := s.usage[urlKey(t, userID)]; ok && u.Day.Equal(day) {
+		usage.Created = u.Created
+	}
+	return usage, nil
+}
+
+// Метод для создания ссылок с проверкой квот. Проверка, создание ссылок и увеличение дневного счетчика
+// выполняются под одной блокировкой, поэтому параллельные запросы не превышают квоты
+func (s *Storage) CreateWithQuota(ctx context.Context, urls []model.URL, quota model.Quota) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := tenantID(ctx)
+	n := len(urls)
+	if quota.MaxLinks > 0 && s.count(t)+n > quota.MaxLinks {
+		return ErrTenantQuota
+	}
+
+	var usage dailyUsage
+	if quota.UserID != "" {
+		if quota.ActiveLimit > 0 && s.active(t, quota.UserID)+n > quota.ActiveLimit {
+			return ErrActiveQuota
+		}
+		usage = s.usage[urlKey(t, quota.UserID)]
+		if !usage.Day.Equal(quota.Day) {
+			usage = dailyUsage{Tenant: t, UserID: quota.UserID, Day: quota.Day}
+		}
+		if quota.DailyLimit > 0 && usage.Created+n > quota.DailyLimit {
+			return ErrDailyQuota
+		}
+	}
+
+	//Повторы проверяем до создания, чтобы ссылки создавались все или ни одной, как в транзакции БД
+	batch := map[string]string{}
+	for i := range urls {
+		if urls[i].Tenant == "" {
+			urls[i].Tenant = t
+		}
+		if u, ok := s.duplicate(urls[i]); ok {
+			return &URLExistsError{ShortURL: u.ID, Er: "Original URL already in DB"}
+		}
+		if !urls[i].Shareable() {
+			continue
+		}
+		if id, ok := batch[urls[i].FullURL]; ok {
+			return &URLExistsError{ShortURL: id, Er: "Original URL already in DB"}
+		}
+		batch[urls[i].FullURL] = urls[i].ID
+	}
+
+	for i := range urls {
+		if err := s.create(ctx, &urls[i]); err != nil {
+			return err
+		}
+	}
+	if quota.UserID == "" {
+		return nil
+	}
+
+	usage.Created += n
+	s.usage[urlKey(t, quota.UserID)] = usage
+	if s.file != nil {
+		return appendJSONLine(usagePath(s.file.Name()), usage)
+	}
+	return nil
+}
+
+// Число ссылок тенанта. Вызывается под блокировкой
+func (s *Storage) count(tenantID string) int {
+	n := 0
+	for _, u := range s.db {
+		if u.Tenant == tenantID {
+			n++
+		}
+	}
+	return n
+}
+
+// Число не отключенных ссылок пользователя в тенанте. Вызывается под блокировкой
+func (s *Storage) active(tenantID, userID string) int {
+	n := 0
+	for _, u := range s.db {
+		if u.Tenant == tenantID && u.UserID == userID && !u.Disabled {
+			n++
+		}
+	}
+	return n
+}
+
+// Счетчик ссылок, созданных пользователем за сутки. Хранится отдельно от ссылок,
+// поэтому удаление ссылки не возвращает ее в дневную квоту
+type dailyUsage struct {
+	Tenant  string    `json:"tenant,omitempty"`
+	UserID  string    `json:"user_id"`
+	Day     time.Time `json:"day"`
+	Created int       `json:"created"`
+}
+
+// Загружает дневные счетчики пользователей из файла рядом с основным, если он есть.
+// Более поздняя строка заменяет счетчик пользователя
+func (s *Storage) fillUsage(name string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		var u dailyUsage
+		if err := json.Unmarshal(scan.Bytes(), &u); err != nil {
+			return err
+		}
+		s.usage[urlKey(u.Tenant, u.UserID)] = u
+	}
+	return scan.Err()
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+)
+
+func TestDeleteRemovesHistoryFromFile(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "urls.json")
+
+	s := openFileStorage(t, name)
+	for _, u := range []*model.URL{
+		model.NewURL("U8rtGB25", "https://practicum.yandex.ru/"),
+		model.NewURL("g7RETf01", "https://mail.ru/"),
+	} {
+		if err := s.Create(ctx, u); err != nil {
+			t.Fatalf("Error during creating record: %v", err)
+		}
+	}
+	for _, id := range []string{"U8rtGB25", "g7RETf01"} {
+		if _, err := s.Retarget(ctx, id, "https://"+id+".example/", "owner"); err != nil {
+			t.Fatalf("Error during retarget: %v", err)
+		}
+	}
+
+	if err := s.Delete(ctx, "U8rtGB25"); err != nil {
+		t.Fatalf("Error during delete: %v", err)
+	}
+	//Запись с тем же ID создается заново после удаления
+	if err := s.Create(ctx, model.NewURL("U8rtGB25", "https://ya.ru/")); err != nil {
+		t.Fatalf("Error during creating record: %v", err)
+	}
+
+	//После перезагрузки из файла история удаленной записи не возвращается, а остальных сохраняется
+	reloaded := openFileStorage(t, name)
+	history, err := reloaded.History(ctx, "U8rtGB25")
+	if err != nil {
+		t.Fatalf("Error during reading history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("History of deleted record didn't match expected: got %+v want empty", history)
+	}
+	history, err = reloaded.History(ctx, "g7RETf01")
+	if err != nil {
+		t.Fatalf("Error during reading history: %v", err)
+	}
+	if len(history) != 1 || history[0].NewURL != "https://g7RETf01.example/" {
+		t.Errorf("History of kept record didn't match expected: got %+v", history)
+	}
+}
+
+// Открывает хранилище с загрузкой из файла name
+func openFileStorage(t *testing.T, name string) *Storage {
+	t.Helper()
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("Error during opening file: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	s := NewStorage(map[string]model.URL{})
+	if err := s.FillFromFile(file); err != nil {
+		t.Fatalf("Error during reading file: %v", err)
+	}
+	s.SetFile(file)
+	return s
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/IgorGreusunset/shortener/internal/storage"
+
+// Выполняет запросы: *sql.DB или *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Открывает span для SQL-запроса с его текстом
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return otel.Tracer(tracerName).Start(ctx, "postgres."+strings.ToUpper(operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", strings.ToUpper(operation)),
+			attribute.String("db.statement", query),
+		))
+}
+
+// Закрывает span запроса, записывая число строк и ошибку. Отсутствие строк ошибкой не считается
+func endQuerySpan(span trace.Span, rows int64, err error) {
+	span.SetAttributes(attribute.Int64("db.rows", rows))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ExecContext в span с числом затронутых строк
+func execContext(ctx context.Context, q querier, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+
+	res, err := q.ExecContext(ctx, query, args...)
+	var n int64
+	if err == nil {
+		n, _ = res.RowsAffected()
+	}
+
+	endQuerySpan(span, n, err)
+	return res, err
+}
+
+// QueryRowContext в span, который закрывается при чтении строки
+func queryRowContext(ctx context.Context, q querier, query string, args ...any) *tracedRow {
+	ctx, span := startQuerySpan(ctx, query)
+	return &tracedRow{row: q.QueryRowContext(ctx, query, args...), span: span}
+}
+
+// QueryContext в span, который закрывается вместе с результатом и содержит число прочитанных строк
+func queryContext(ctx context.Context, q querier, query string, args ...any) (*tracedRows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		endQuerySpan(span, 0, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+type tracedRow struct {
+	row  *sql.Row
+	span trace.Span
+}
+
+func (r *tracedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	var n int64
+	if err == nil {
+		n = 1
+	}
+	endQuerySpan(r.span, n, err)
+	return err
+}
+
+type tracedRows struct {
+	*sql.Rows
+	span   trace.Span
+	n      int64
+	closed bool
+}
+
+func (r *tracedRows) Next() bool {
+	ok := r.Rows.Next()
+	if ok {
+		r.n++
+	}
+	return ok
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		endQuerySpan(r.span, r.n, r.Rows.Err())
+	}
+	return err
+}
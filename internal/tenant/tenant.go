@@ -0,0 +1,125 @@
+// Пакет tenant описывает бренды сервиса: у каждого свой короткий домен, пространство коротких ID и квоты.
+// Тенант запроса определяется по заголовку Host и передается через контекст, хранилище ограничивает им
+// все операции с короткими ID
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+)
+
+// Тенант с собственным доменом коротких ссылок
+type Tenant struct {
+	//Пустой ID у тенанта по умолчанию, к нему относятся ссылки, созданные до появления тенантов
+	ID      string   `json:"id"`
+	Hosts   []string `json:"hosts"`
+	BaseURL string   `json:"base_url"`
+	//Максимальное число ссылок тенанта, 0 - без ограничения
+	MaxLinks int `json:"max_links,omitempty"`
+}
+
+// ID тенанта хранится рядом с коротким ID, поэтому ограничиваем его набор символов и длину
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// Набор тенантов с поиском по домену
+type Registry struct {
+	byHost map[string]Tenant
+	def    Tenant
+}
+
+// Фабричный метод создания набора тенантов. Запросы на неизвестные домены относятся к тенанту def
+func NewRegistry(def Tenant, tenants []Tenant) (*Registry, error) {
+	r := &Registry{byHost: map[string]Tenant{}, def: def}
+	ids := map[string]bool{}
+
+	for _, t := range tenants {
+		if !validID.MatchString(t.ID) {
+			return nil, fmt.Errorf("tenant %q: id must match %s", t.ID, validID)
+		}
+		if ids[t.ID] {
+			return nil, fmt.Errorf("tenant %q: duplicate id", t.ID)
+		}
+		ids[t.ID] = true
+
+		base, err := url.Parse(t.BaseURL)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("tenant %q: invalid base_url %q", t.ID, t.BaseURL)
+		}
+		t.BaseURL = strings.TrimSuffix(t.BaseURL, "/")
+
+		//Домен из base_url обслуживается всегда, hosts добавляют к нему дополнительные
+		hosts := append([]string{base.Host}, t.Hosts...)
+		for _, h := range hosts {
+			h = normalizeHost(h)
+			if other, ok := r.byHost[h]; ok && other.ID != t.ID {
+				return nil, fmt.Errorf("tenant %q: host %s already belongs to tenant %q", t.ID, h, other.ID)
+			}
+			r.byHost[h] = t
+		}
+	}
+
+	return r, nil
+}
+
+// Загружает тенантов из JSON-файла со списком объектов Tenant
+func Load(path string, def Tenant) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, err
+	}
+	return NewRegistry(def, tenants)
+}
+
+// Находит тенанта по значению заголовка Host
+func (r *Registry) Resolve(host string) Tenant {
+	if t, ok := r.byHost[normalizeHost(host)]; ok {
+		return t
+	}
+	return r.def
+}
+
+// Приводит домен к виду для сравнения: без порта, точки в конце и в нижнем регистре
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// Тенант по умолчанию с базовым адресом из конфигурации
+func Default() Tenant {
+	return Tenant{BaseURL: config.Base}
+}
+
+type ctxKey struct{}
+
+// Кладет тенанта в контекст запроса
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// Достает тенанта из контекста запроса, без него возвращает тенанта по умолчанию
+func FromContext(ctx context.Context) Tenant {
+	if t, ok := ctx.Value(ctxKey{}).(Tenant); ok {
+		return t
+	}
+	return Default()
+}
+
+// Короткая ссылка на домене тенанта
+func (t Tenant) ShortURL(id string) string {
+	return t.BaseURL + `/` + id
+}
@@ -0,0 +1,76 @@
+package tenant
+
+import (
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	def := Tenant{BaseURL: "http://localhost:8080"}
+	reg, err := NewRegistry(def, []Tenant{
+		{ID: "alpha", BaseURL: "https://a.example/", Hosts: []string{"www.a.example"}},
+		{ID: "beta", BaseURL: "https://b.example"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "a.example", expected: "alpha"},
+		{host: "WWW.A.Example:443", expected: "alpha"},
+		{host: "b.example.", expected: "beta"},
+		{host: "localhost:8080", expected: ""},
+		{host: "unknown.example", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got := reg.Resolve(tt.host)
+			if got.ID != tt.expected {
+				t.Errorf("Tenant didn't match expected: got %q want %q", got.ID, tt.expected)
+			}
+		})
+	}
+
+	if got := reg.Resolve("a.example").ShortURL("abc"); got != "https://a.example/abc" {
+		t.Errorf("Short url didn't match expected: got %s", got)
+	}
+}
+
+func TestRegistryInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants []Tenant
+	}{
+		{
+			name:    "empty_id",
+			tenants: []Tenant{{BaseURL: "https://a.example"}},
+		},
+		{
+			name:    "bad_id",
+			tenants: []Tenant{{ID: "Alpha/1", BaseURL: "https://a.example"}},
+		},
+		{
+			name:    "duplicate_id",
+			tenants: []Tenant{{ID: "alpha", BaseURL: "https://a.example"}, {ID: "alpha", BaseURL: "https://b.example"}},
+		},
+		{
+			name:    "shared_host",
+			tenants: []Tenant{{ID: "alpha", BaseURL: "https://a.example"}, {ID: "beta", BaseURL: "https://b.example", Hosts: []string{"a.example"}}},
+		},
+		{
+			name:    "bad_base_url",
+			tenants: []Tenant{{ID: "alpha", BaseURL: "a.example"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegistry(Tenant{}, tt.tenants); err == nil {
+				t.Errorf("Expected error for invalid tenants")
+			}
+		})
+	}
+}
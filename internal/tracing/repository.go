@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Декоратор хранилища, создающий span на каждую операцию
+type Repository struct {
+	next    storage.Repository
+	backend string
+}
+
+var _ storage.Repository = (*Repository)(nil)
+
+// Оборачивает хранилище, backend записывается в атрибуты span (file, postgres)
+func NewRepository(next storage.Repository, backend string) *Repository {
+	return &Repository{next: next, backend: backend}
+}
+
+// Начинает span операции method
+func (r *Repository) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("storage.backend", r.backend))
+	if t := tenant.FromContext(ctx).ID; t != "" {
+		attrs = append(attrs, attribute.String("tenant.id", t))
+	}
+	return Tracer().Start(ctx, "storage."+method, trace.WithAttributes(attrs...))
+}
+
+// Завершает span, отмечая ошибку. Ненайденная запись и исчерпанный лимит переходов ошибками хранилища не считаются.
+// Ошибка передается указателем, чтобы в defer прочитать итоговое значение
+func end(span trace.Span, err *error) {
+	if err != nil && *err != nil && !errors.Is(*err, storage.ErrNotFound) && !errors.Is(*err, storage.ErrClicksExhausted) {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+func (r *Repository) Create(ctx context.Context, record *model.URL) (err error) {
+	ctx, span := r.start(ctx, "Create", attribute.String("url.id", record.ID))
+	defer end(span, &err)
+	return r.next.Create(ctx, record)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id string) (model.URL, bool) {
+	ctx, span := r.start(ctx, "GetByID", attribute.String("url.id", id))
+	defer span.End()
+
+	u, ok := r.next.GetByID(ctx, id)
+	span.SetAttributes(attribute.Bool("url.found", ok))
+	return u, ok
+}
+
+func (r *Repository) Ping() (err error) {
+	_, span := r.start(context.Background(), "Ping")
+	defer end(span, &err)
+	return r.next.Ping()
+}
+
+func (r *Repository) CreateBatch(ctx context.Context, urls []model.URL) (err error) {
+	ctx, span := r.start(ctx, "CreateBatch", attribute.Int("batch.size", len(urls)))
+	defer end(span, &err)
+	return r.next.CreateBatch(ctx, urls)
+}
+
+func (r *Repository) Walk(ctx context.Context, fn func(model.URL) error) (err error) {
+	ctx, span := r.start(ctx, "Walk")
+	defer end(span, &err)
+	return r.next.Walk(ctx, fn)
+}
+
+func (r *Repository) List(ctx context.Context, query string) (urls []model.URL, err error) {
+	ctx, span := r.start(ctx, "List")
+	defer end(span, &err)
+
+	urls, err = r.next.List(ctx, query)
+	span.SetAttributes(attribute.Int("result.count", len(urls)))
+	return urls, err
+}
+
+func (r *Repository) ListByUser(ctx context.Context, userID string) (urls []model.URL, err error) {
+	ctx, span := r.start(ctx, "ListByUser")
+	defer end(span, &err)
+
+	urls, err = r.next.ListByUser(ctx, userID)
+	span.SetAttributes(attribute.Int("result.count", len(urls)))
+	return urls, err
+}
+
+func (r *Repository) Update(ctx context.Context, record *model.URL) (err error) {
+	ctx, span := r.start(ctx, "Update", attribute.String("url.id", record.ID))
+	defer end(span, &err)
+	return r.next.Update(ctx, record)
+}
+
+func (r *Repository) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := r.start(ctx, "Delete", attribute.String("url.id", id))
+	defer end(span, &err)
+	return r.next.Delete(ctx, id)
+}
+
+func (r *Repository) Retarget(ctx context.Context, id, newURL, actor string) (u model.URL, err error) {
+	ctx, span := r.start(ctx, "Retarget", attribute.String("url.id", id))
+	defer end(span, &err)
+	return r.next.Retarget(ctx, id, newURL, actor)
+}
+
+func (r *Repository) History(ctx context.Context, id string) (revs []model.Revision, err error) {
+	ctx, span := r.start(ctx, "History", attribute.String("url.id", id))
+	defer end(span, &err)
+	return r.next.History(ctx, id)
+}
+
+func (r *Repository) RegisterClick(ctx context.Context, id string) (err error) {
+	ctx, span := r.start(ctx, "RegisterClick", attribute.String("url.id", id))
+	defer end(span, &err)
+	return r.next.RegisterClick(ctx, id)
+}
+
+func (r *Repository) CreateAPIKey(ctx context.Context, key *model.APIKey) (err error) {
+	ctx, span := r.start(ctx, "CreateAPIKey", attribute.String("apikey.id", key.ID))
+	defer end(span, &err)
+	return r.next.CreateAPIKey(ctx, key)
+}
+
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, hash string) (key model.APIKey, err error) {
+	ctx, span := r.start(ctx, "GetAPIKeyByHash")
+	defer end(span, &err)
+	return r.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (r *Repository) ListAPIKeys(ctx context.Context, userID string) (keys []model.APIKey, err error) {
+	ctx, span := r.start(ctx, "ListAPIKeys")
+	defer end(span, &err)
+
+	keys, err = r.next.ListAPIKeys(ctx, userID)
+	span.SetAttributes(attribute.Int("result.count", len(keys)))
+	return keys, err
+}
+
+func (r *Repository) RevokeAPIKey(ctx context.Context, userID, id string) (err error) {
+	ctx, span := r.start(ctx, "RevokeAPIKey", attribute.String("apikey.id", id))
+	defer end(span, &err)
+	return r.next.RevokeAPIKey(ctx, userID, id)
+}
+
+func (r *Repository) Count(ctx context.Context) (n int, err error) {
+	ctx, span := r.start(ctx, "Count")
+	defer end(span, &err)
+	return r.next.Count(ctx)
+}
+
+func (r *Repository) Usage(ctx context.Context, userID string, day time.Time) (u model.Usage, err error) {
+	ctx, span := r.start(ctx, "Usage")
+	defer end(span, &err)
+	return r.next.Usage(ctx, userID, day)
+}
+
+func (r *Repository) CreateWithQuota(ctx context.Context, urls []model.URL, quota model.Quota) (err error) {
+	ctx, span := r.start(ctx, "CreateWithQuota", attribute.Int("batch.size", len(urls)))
+	defer end(span, &err)
+	return r.next.CreateWithQuota(ctx, urls, quota)
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/IgorGreusunset/shortener"
+
+// Трассировщик сервиса, берется из глобального провайдера при каждом вызове,
+// чтобы учитывать провайдер, установленный после инициализации пакета
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Настраивает глобальный провайдер с экспортом по OTLP/HTTP на endpoint и долей сэмплирования ratio.
+// Пропагатор W3C traceparent и baggage устанавливается всегда, даже без экспорта.
+// Возвращает функцию, досылающую накопленные span при остановке
+func Init(ctx context.Context, endpoint string, insecure bool, ratio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	//Без адреса коллектора остается noop-провайдер по умолчанию
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(exporter, ratio)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Создает провайдер, отправляющий span в exporter. В тестах используется с tracetest.InMemoryExporter
+func NewProvider(exporter sdktrace.SpanExporter, ratio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+}
+
+// Оборачивает handler дочерним span name, отделяя время логики обработчика от middleware
+func Handler(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		ctx, span := Tracer().Start(req.Context(), name)
+		defer span.End()
+
+		h(res, req.WithContext(ctx))
+	}
+}
@@ -0,0 +1,72 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, 1)
+	otel.SetTracerProvider(provider)
+	if _, err := tracing.Init(context.Background(), "", false, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := tracing.NewRepository(storage.NewStorage(map[string]model.URL{
+		"U8rtGB25": *model.NewURL("U8rtGB25", "https://mail.ru/"),
+	}), "memory")
+
+	router := chi.NewRouter()
+	router.Use(middleware.WithTracing)
+	router.Get(`/{id}`, tracing.Handler("GetByIDHandler", func(res http.ResponseWriter, req *http.Request) {
+		if _, ok := repo.GetByID(req.Context(), chi.URLParam(req, "id")); !ok {
+			http.Error(res, "Not found", http.StatusNotFound)
+			return
+		}
+		res.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/U8rtGB25", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.GetSpans()
+
+	//Span завершаются изнутри наружу: хранилище, handler, запрос
+	want := []string{"storage.GetByID", "GetByIDHandler", "GET /{id}"}
+	if len(spans) != len(want) {
+		t.Fatalf("Span count didn't match expected: got %d want %d", len(spans), len(want))
+	}
+	for i, span := range spans {
+		if span.Name != want[i] {
+			t.Errorf("Span name didn't match expected: got %s want %s", span.Name, want[i])
+		}
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("Trace id didn't match traceparent: got %s want %s", span.SpanContext.TraceID(), traceID)
+		}
+	}
+
+	//Каждый span вложен в предыдущий по уровню
+	if spans[0].Parent.SpanID() != spans[1].SpanContext.SpanID() || spans[1].Parent.SpanID() != spans[2].SpanContext.SpanID() {
+		t.Errorf("Spans are not nested: storage -> handler -> request")
+	}
+	if spans[2].Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Request span parent didn't match traceparent: got %s", spans[2].Parent.SpanID())
+	}
+}
@@ -0,0 +1,266 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+)
+
+// Поддерживаемые форматы выгрузки
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Заголовок CSV-выгрузки, порядок колонок совпадает с csvRecord.
+// Колонки tenant, password_hash и max_clicks добавлены последними: выгрузки без них загружаются
+// в тенант по умолчанию без паролей и ограничений переходов
+var csvHeader = []string{"uuid", "short_url", "original_url", "user_id", "created", "disabled", "redirect_code", "clicks", "tenant", "password_hash", "max_clicks"}
+
+// Число обязательных колонок в начале csvHeader
+const csvRequired = 8
+
+// Конфликт, возникший при загрузке записи
+type Conflict struct {
+	Record model.URL
+	Reason string
+}
+
+// Итог загрузки записей в хранилище
+type Report struct {
+	Imported  int
+	Conflicts []Conflict
+}
+
+// Выгружает все записи хранилища в w в заданном формате, возвращает количество выгруженных записей
+func Export(ctx context.Context, repo storage.Repository, w io.Writer, format string) (int, error) {
+	var (
+		count int
+		write func(model.URL) error
+		flush func() error
+	)
+
+	switch format {
+	case FormatJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		write = func(u model.URL) error { return enc.Encode(u) }
+		flush = bw.Flush
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return 0, err
+		}
+		write = func(u model.URL) error { return cw.Write(csvRecord(u)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	err := repo.Walk(ctx, func(u model.URL) error {
+		if err := write(u); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
+
+// Загружает записи из r в хранилище, сохраняя короткие ID и UUID. Записи, конфликтующие с уже существующими, пропускаются и попадают в отчет.
+// Колонки CSV сопоставляются по заголовку, поэтому их порядок может отличаться от выгрузки
+func Import(ctx context.Context, repo storage.Repository, r io.Reader, format string) (*Report, error) {
+	var read func() (model.URL, error)
+
+	switch format {
+	case FormatJSONL:
+		dec := json.NewDecoder(r)
+		read = func() (model.URL, error) {
+			var u model.URL
+			err := dec.Decode(&u)
+			return u, err
+		}
+	case FormatCSV:
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return &Report{}, nil
+			}
+			return nil, err
+		}
+		columns, err := csvColumns(header)
+		if err != nil {
+			return nil, err
+		}
+		cr.FieldsPerRecord = len(header)
+		read = func() (model.URL, error) {
+			rec, err := cr.Read()
+			if err != nil {
+				return model.URL{}, err
+			}
+			return parseCSVRecord(rec, columns)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	report := &Report{}
+	for {
+		u, err := read()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		//Короткий ID проверяется в пространстве тенанта записи
+		ctx := tenant.WithTenant(ctx, tenant.Tenant{ID: u.Tenant})
+		if existing, ok := repo.GetByID(ctx, u.ID); ok {
+			report.Conflicts = append(report.Conflicts, Conflict{
+				Record: u,
+				Reason: "short url already exists for " + existing.FullURL,
+			})
+			continue
+		}
+
+		if err := repo.Create(ctx, &u); err != nil {
+			var uee *storage.URLExistsError
+			if errors.As(err, &uee) {
+				report.Conflicts = append(report.Conflicts, Conflict{
+					Record: u,
+					Reason: "original url already stored as " + uee.ShortURL,
+				})
+				continue
+			}
+			if errors.Is(err, storage.ErrUUIDExists) {
+				report.Conflicts = append(report.Conflicts, Conflict{
+					Record: u,
+					Reason: "uuid " + strconv.Itoa(u.UUID) + " already used by another record",
+				})
+				continue
+			}
+			return report, err
+		}
+		report.Imported++
+	}
+}
+
+func csvRecord(u model.URL) []string {
+	return []string{
+		strconv.Itoa(u.UUID),
+		u.ID,
+		u.FullURL,
+		u.UserID,
+		u.Created.Format(time.RFC3339Nano),
+		strconv.FormatBool(u.Disabled),
+		strconv.Itoa(u.RedirectCode),
+		strconv.Itoa(u.Clicks),
+		u.Tenant,
+		u.PasswordHash,
+		strconv.Itoa(u.MaxClicks),
+	}
+}
+
+// Сопоставляет колонки CSV по именам из заголовка. Обязательны первые csvRequired колонок csvHeader,
+// неизвестные и повторяющиеся колонки считаются ошибкой
+func csvColumns(header []string) (map[string]int, error) {
+	known := map[string]bool{}
+	for _, name := range csvHeader {
+		known[name] = true
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if !known[name] {
+			return nil, fmt.Errorf("unexpected CSV column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		columns[name] = i
+	}
+
+	for _, name := range csvHeader[:csvRequired] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// Разбирает строку CSV по колонкам из csvColumns. Отсутствующие необязательные колонки остаются пустыми
+func parseCSVRecord(rec []string, columns map[string]int) (model.URL, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return rec[i]
+		}
+		return ""
+	}
+
+	uuid, err := strconv.Atoi(field("uuid"))
+	if err != nil {
+		return model.URL{}, fmt.Errorf("invalid uuid %q: %w", field("uuid"), err)
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, field("created"))
+	if err != nil {
+		return model.URL{}, fmt.Errorf("invalid created %q: %w", field("created"), err)
+	}
+
+	disabled, err := strconv.ParseBool(field("disabled"))
+	if err != nil {
+		return model.URL{}, fmt.Errorf("invalid disabled %q: %w", field("disabled"), err)
+	}
+
+	redirectCode, err := strconv.Atoi(field("redirect_code"))
+	if err != nil {
+		return model.URL{}, fmt.Errorf("invalid redirect_code %q: %w", field("redirect_code"), err)
+	}
+
+	clicks, err := strconv.Atoi(field("clicks"))
+	if err != nil {
+		return model.URL{}, fmt.Errorf("invalid clicks %q: %w", field("clicks"), err)
+	}
+
+	var maxClicks int
+	if v := field("max_clicks"); v != "" {
+		if maxClicks, err = strconv.Atoi(v); err != nil {
+			return model.URL{}, fmt.Errorf("invalid max_clicks %q: %w", v, err)
+		}
+	}
+
+	return model.URL{
+		UUID:         uuid,
+		ID:           field("short_url"),
+		FullURL:      field("original_url"),
+		UserID:       field("user_id"),
+		Created:      created,
+		Disabled:     disabled,
+		RedirectCode: redirectCode,
+		Clicks:       clicks,
+		Tenant:       field("tenant"),
+		PasswordHash: field("password_hash"),
+		MaxClicks:    maxClicks,
+	}, nil
+}
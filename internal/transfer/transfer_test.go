@@ -0,0 +1,177 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/IgorGreusunset/shortener/internal/tenant"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportImport(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	records := []model.URL{
+		{UUID: 5, ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", UserID: "user1", Created: created, RedirectCode: 301},
+		{UUID: 7, ID: "g7RETf01", FullURL: "https://mail.ru/", Created: created.Add(time.Hour)},
+		//Тот же короткий ID в другом тенанте не конфликтует с записью тенанта по умолчанию
+		{UUID: 9, ID: "g7RETf01", FullURL: "https://mail.ru/", Created: created.Add(2 * time.Hour), Tenant: "brand"},
+	}
+
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			src := storage.NewStorage(map[string]model.URL{})
+			for _, r := range records {
+				r := r
+				if err := src.Create(ctx, &r); err != nil {
+					t.Fatalf("Error during filling source storage: %v", err)
+				}
+			}
+
+			var buf bytes.Buffer
+			count, err := Export(ctx, src, &buf, format)
+			if err != nil {
+				t.Fatalf("Error during export: %v", err)
+			}
+			if count != len(records) {
+				t.Errorf("Exported count didn't match expected: got %d want %d", count, len(records))
+			}
+
+			//В целевом хранилище уже есть запись с одним из коротких ID
+			dst := storage.NewStorage(map[string]model.URL{})
+			if err := dst.Create(ctx, model.NewURL("g7RETf01", "https://ya.ru/")); err != nil {
+				t.Fatalf("Error during filling target storage: %v", err)
+			}
+
+			report, err := Import(ctx, dst, &buf, format)
+			if err != nil {
+				t.Fatalf("Error during import: %v", err)
+			}
+			if report.Imported != 2 {
+				t.Errorf("Imported count didn't match expected: got %d want %d", report.Imported, 2)
+			}
+			if len(report.Conflicts) != 1 || report.Conflicts[0].Record.ID != "g7RETf01" {
+				t.Errorf("Conflicts didn't match expected: %+v", report.Conflicts)
+			}
+
+			got, ok := dst.GetByID(ctx, "U8rtGB25")
+			if !ok {
+				t.Fatalf("Imported record not found")
+			}
+			if diff := cmp.Diff(records[0], got); diff != "" {
+				t.Errorf("Imported record didn't match expected: (-want +got)\n%s", diff)
+			}
+
+			got, ok = dst.GetByID(tenant.WithTenant(ctx, tenant.Tenant{ID: "brand"}), "g7RETf01")
+			if !ok {
+				t.Fatalf("Imported tenant record not found")
+			}
+			if diff := cmp.Diff(records[2], got); diff != "" {
+				t.Errorf("Imported tenant record didn't match expected: (-want +got)\n%s", diff)
+			}
+
+			//Новые записи получают UUID после загруженных
+			next := model.NewURL("Nx7pQ2aa", "https://go.dev/")
+			if err := dst.Create(ctx, next); err != nil {
+				t.Fatalf("Error during creating record after import: %v", err)
+			}
+			if next.UUID <= records[2].UUID {
+				t.Errorf("UUID after import didn't match expected: got %d want greater than %d", next.UUID, records[2].UUID)
+			}
+
+			//Повторная загрузка не создает записи с занятыми UUID
+			var again bytes.Buffer
+			if _, err := Export(ctx, src, &again, format); err != nil {
+				t.Fatalf("Error during export: %v", err)
+			}
+			other := storage.NewStorage(map[string]model.URL{})
+			taken := model.URL{UUID: 5, ID: "Tk5aaaaa", FullURL: "https://example.com/"}
+			if err := other.Create(ctx, &taken); err != nil {
+				t.Fatalf("Error during filling target storage: %v", err)
+			}
+			report, err = Import(ctx, other, &again, format)
+			if err != nil {
+				t.Fatalf("Error during import: %v", err)
+			}
+			if report.Imported != 2 || len(report.Conflicts) != 1 || report.Conflicts[0].Record.UUID != 5 {
+				t.Errorf("Import into storage with taken uuid didn't match expected: %+v", report)
+			}
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	src := storage.NewStorage(map[string]model.URL{})
+	if _, err := Export(context.Background(), src, &bytes.Buffer{}, "xml"); err == nil {
+		t.Errorf("Expected error for unknown format")
+	}
+}
+
+func TestImportCSVHeader(t *testing.T) {
+	const row = "2024-05-01T10:00:00Z"
+
+	tests := []struct {
+		name     string
+		input    string
+		expected model.URL
+		wantErr  bool
+	}{
+		{
+			name:     "without_optional_columns",
+			input:    "uuid,short_url,original_url,user_id,created,disabled,redirect_code,clicks\n3,U8rtGB25,https://practicum.yandex.ru/,user1," + row + ",false,301,4\n",
+			expected: model.URL{UUID: 3, ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", UserID: "user1", RedirectCode: 301, Clicks: 4},
+		},
+		{
+			name:     "reordered",
+			input:    "short_url,uuid,max_clicks,original_url,clicks,created,user_id,redirect_code,disabled\nU8rtGB25,3,1,https://practicum.yandex.ru/,4," + row + ",user1,301,true\n",
+			expected: model.URL{UUID: 3, ID: "U8rtGB25", FullURL: "https://practicum.yandex.ru/", UserID: "user1", RedirectCode: 301, Clicks: 4, Disabled: true, MaxClicks: 1},
+		},
+		{
+			name:    "unknown_column",
+			input:   "uuid,short_url,full_url,user_id,created,disabled,redirect_code,clicks\n3,U8rtGB25,https://practicum.yandex.ru/,user1," + row + ",false,301,4\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing_column",
+			input:   "uuid,short_url,original_url,user_id,created,disabled,redirect_code\n3,U8rtGB25,https://practicum.yandex.ru/,user1," + row + ",false,301\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate_column",
+			input:   "uuid,short_url,original_url,user_id,created,disabled,redirect_code,clicks,clicks\n3,U8rtGB25,https://practicum.yandex.ru/,user1," + row + ",false,301,4,4\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dst := storage.NewStorage(map[string]model.URL{})
+
+			_, err := Import(ctx, dst, strings.NewReader(tt.input), FormatCSV)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error for header %q", strings.SplitN(tt.input, "\n", 2)[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error during import: %v", err)
+			}
+
+			got, ok := dst.GetByID(ctx, tt.expected.ID)
+			if !ok {
+				t.Fatalf("Imported record not found")
+			}
+			tt.expected.Created, _ = time.Parse(time.RFC3339, row)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Imported record didn't match expected: (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
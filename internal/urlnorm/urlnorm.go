@@ -0,0 +1,116 @@
+package urlnorm
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid url")
+	ErrSchemeNotAllowed = errors.New("url scheme is not allowed")
+	ErrHostMissing      = errors.New("url host is missing")
+)
+
+// Порты по умолчанию, которые удаляются из адреса
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Настройки проверки и нормализации ссылок
+type Options struct {
+	//Разрешенные схемы, пустой список означает http и https
+	AllowedSchemes []string
+	//Приводить ссылку к каноническому виду
+	Normalize bool
+	//Сортировать параметры запроса
+	SortQuery bool
+}
+
+// Проверяет ссылку и, если включено, приводит ее к каноническому виду:
+// схема и хост в нижнем регистре, порт по умолчанию удален, IDN-хост в punycode,
+// пустой путь заменен на "/", параметры запроса отсортированы по желанию
+func Normalize(raw string, opts Options) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if !schemeAllowed(scheme, opts.AllowedSchemes) {
+		return "", ErrSchemeNotAllowed
+	}
+	if u.Host == "" {
+		return "", ErrHostMissing
+	}
+
+	if !opts.Normalize {
+		return raw, nil
+	}
+
+	host, err := NormalizeHost(u.Hostname())
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	port := u.Port()
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		//IPv6-адрес без порта
+		host = "[" + host + "]"
+	}
+
+	u.Scheme = scheme
+	u.Host = host
+	if u.Path == "" && u.RawPath == "" {
+		u.Path = "/"
+	}
+	if opts.SortQuery && u.RawQuery != "" {
+		u.RawQuery = sortQuery(u.RawQuery)
+	}
+
+	return u.String(), nil
+}
+
+func schemeAllowed(scheme string, allowed []string) bool {
+	if len(allowed) == 0 {
+		allowed = []string{"http", "https"}
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Приводит хост к нижнему регистру и ASCII-представлению без завершающей точки.
+// Используется и для сравнения хостов с правилами политики доменов
+func NormalizeHost(host string) (string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
+
+// Сортирует параметры запроса по имени, сохраняя порядок значений одного параметра
+func sortQuery(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	sort.SliceStable(parts, func(i, j int) bool {
+		ki, _, _ := strings.Cut(parts[i], "=")
+		kj, _, _ := strings.Cut(parts[j], "=")
+		return ki < kj
+	})
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,115 @@
+package urlnorm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	defaults := Options{Normalize: true}
+
+	tests := []struct {
+		name        string
+		raw         string
+		opts        Options
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "already_normal",
+			raw:      "https://mail.ru/",
+			opts:     defaults,
+			expected: "https://mail.ru/",
+		},
+		{
+			name:     "case_and_empty_path",
+			raw:      "HTTPS://Mail.RU",
+			opts:     defaults,
+			expected: "https://mail.ru/",
+		},
+		{
+			name:     "default_port",
+			raw:      "http://mail.ru:80/inbox?b=2&a=1",
+			opts:     defaults,
+			expected: "http://mail.ru/inbox?b=2&a=1",
+		},
+		{
+			name:     "custom_port",
+			raw:      "https://mail.ru:8443/",
+			opts:     defaults,
+			expected: "https://mail.ru:8443/",
+		},
+		{
+			name:     "idn",
+			raw:      "https://пример.рф/путь",
+			opts:     defaults,
+			expected: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C",
+		},
+		{
+			name:     "ipv6",
+			raw:      "http://[::1]:80/",
+			opts:     defaults,
+			expected: "http://[::1]/",
+		},
+		{
+			name:     "trimmed_without_normalization",
+			raw:      "  https://Mail.RU/inbox\n",
+			opts:     Options{},
+			expected: "https://Mail.RU/inbox",
+		},
+		{
+			name:     "sorted_query",
+			raw:      "https://mail.ru/?b=2&a=1&a=0",
+			opts:     Options{Normalize: true, SortQuery: true},
+			expected: "https://mail.ru/?a=1&a=0&b=2",
+		},
+		{
+			name:     "normalization_disabled",
+			raw:      "HTTPS://Mail.RU",
+			opts:     Options{},
+			expected: "HTTPS://Mail.RU",
+		},
+		{
+			name:        "javascript",
+			raw:         "javascript:alert(1)",
+			opts:        defaults,
+			expectedErr: ErrSchemeNotAllowed,
+		},
+		{
+			name:        "ftp",
+			raw:         "ftp://files.example.com/",
+			opts:        defaults,
+			expectedErr: ErrSchemeNotAllowed,
+		},
+		{
+			name:     "ftp_allowed",
+			raw:      "ftp://files.example.com/",
+			opts:     Options{Normalize: true, AllowedSchemes: []string{"ftp"}},
+			expected: "ftp://files.example.com/",
+		},
+		{
+			name:        "no_host",
+			raw:         "http:///path",
+			opts:        defaults,
+			expectedErr: ErrHostMissing,
+		},
+		{
+			name:        "not_url",
+			raw:         "some text not url",
+			opts:        defaults,
+			expectedErr: ErrInvalidURL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw, tt.opts)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Error didn't match expected: got %v want %v", err, tt.expectedErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Normalized url didn't match expected: got %q want %q", got, tt.expected)
+			}
+		})
+	}
+}
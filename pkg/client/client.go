@@ -0,0 +1,313 @@
+// Package client - типизированный клиент HTTP API сокращателя ссылок.
+//
+// Пользователь определяется сервером по cookie user_id. Клиент запоминает cookie, выданную при первом
+// запросе, и отправляет ее дальше; сохраненное значение можно получить через Token и передать
+// в WithToken при следующем запуске.
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/IgorGreusunset/shortener/internal/app"
+)
+
+// Типы запросов и ответов API
+type (
+	ShortenRequest  = model.APIPostRequest
+	ShortenResponse = model.APIPostResponse
+	BatchRequest    = model.APIBatchRequest
+	BatchResponse   = model.APIBatchResponse
+	URLInfo         = model.URLInfo
+)
+
+// Имя cookie с идентификатором пользователя
+const CookieName = "user_id"
+
+// Клиент API. Безопасен для использования из нескольких горутин
+type Client struct {
+	base   *url.URL
+	http   *http.Client
+	retry  RetryPolicy
+	gzip   bool
+	bearer string
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Настройка клиента
+type Option func(*Client)
+
+// Использовать свой http.Client. Переходы по перенаправлениям клиент все равно отключает
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		copied := *hc
+		c.http = &copied
+	}
+}
+
+// Значение cookie user_id, выданной сервером ранее
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// Токен для заголовка Authorization: Bearer
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearer = token
+	}
+}
+
+// Политика повторов запросов
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// Сжимать тела запросов gzip
+func WithGzip() Option {
+	return func(c *Client) {
+		c.gzip = true
+	}
+}
+
+// Фабричный метод клиента для сервера с адресом baseURL, например http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("client: base url must be http or https, got %q", baseURL)
+	}
+
+	c := &Client{
+		base:  base,
+		http:  &http.Client{Timeout: 30 * time.Second},
+		retry: DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	//Resolve должен видеть ответ с перенаправлением, а не страницу назначения
+	c.http.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return c, nil
+}
+
+// Текущее значение cookie пользователя, пустая строка, если сервер ее еще не выдал
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// Создает короткую ссылку. Если ссылка уже сокращена, возвращает ответ с ней вместе с ошибкой *ExistsError
+func (c *Client) Shorten(ctx context.Context, req ShortenRequest) (*ShortenResponse, error) {
+	var resp ShortenResponse
+	res, err := c.do(ctx, http.MethodPost, "/api/shorten", req, &resp, http.StatusCreated, http.StatusConflict)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusConflict {
+		return &resp, &ExistsError{ShortURL: resp.Result}
+	}
+	return &resp, nil
+}
+
+// Создает короткие ссылки пачкой, ответы сопоставляются с запросами по correlation_id
+func (c *Client) ShortenBatch(ctx context.Context, batch []BatchRequest) ([]BatchResponse, error) {
+	var resp []BatchResponse
+	if _, err := c.do(ctx, http.MethodPost, "/api/shorten/batch", batch, &resp, http.StatusCreated); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Результат разрешения короткой ссылки
+type Resolution struct {
+	StatusCode int
+	Location   string
+}
+
+// Возвращает адрес назначения короткой ссылки, не переходя по нему. Переход учитывается сервером как клик.
+// id - ID ссылки или полная короткая ссылка
+func (c *Client) Resolve(ctx context.Context, id string) (*Resolution, error) {
+	res, err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(LinkID(id)), nil, nil,
+		http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolution{StatusCode: res.StatusCode, Location: res.Header.Get("Location")}, nil
+}
+
+// Возвращает сведения о ссылке: адрес назначения, дату создания и число переходов
+func (c *Client) Info(ctx context.Context, id string) (*URLInfo, error) {
+	var info URLInfo
+	if _, err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(LinkID(id))+"/info", nil, &info, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Возвращает ссылки текущего пользователя, пустой список, если их нет
+func (c *Client) ListUserURLs(ctx context.Context) ([]URLInfo, error) {
+	links := []URLInfo{}
+	if _, err := c.do(ctx, http.MethodGet, "/api/user/urls", nil, &links, http.StatusOK, http.StatusNoContent); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Удаляет ссылки текущего пользователя, чужие ссылки сервер пропускает
+func (c *Client) DeleteURLs(ctx context.Context, ids ...string) error {
+	clean := make([]string, 0, len(ids))
+	for _, id := range ids {
+		clean = append(clean, LinkID(id))
+	}
+	_, err := c.do(ctx, http.MethodDelete, "/api/user/urls", clean, nil, http.StatusAccepted)
+	return err
+}
+
+// ID короткой ссылки: сам ID или последний сегмент пути полной короткой ссылки
+func LinkID(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return strings.TrimPrefix(u.Path, "/")
+	}
+	return s
+}
+
+// Выполняет запрос с повторами. Тело in сериализуется в JSON, успешный ответ декодируется в out.
+// Коды из ok считаются успешными, остальные возвращаются как *APIError
+func (c *Client) do(ctx context.Context, method, path string, in, out any, ok ...int) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		if c.gzip {
+			if data, err = gzipBytes(data); err != nil {
+				return nil, err
+			}
+		}
+		body = data
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, payload, err := c.send(ctx, method, path, body)
+
+		retry, wait := c.retry.next(attempt, method, res, err)
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
+			return res, decode(res, payload, out, ok)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Отправляет один запрос и читает тело ответа целиком
+func (c *Client) send(ctx context.Context, method, path string, body []byte) (*http.Response, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.base.String()+path, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+		if c.gzip {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+	}
+	if token := c.Token(); token != "" {
+		req.AddCookie(&http.Cookie{Name: CookieName, Value: token})
+	}
+	if c.bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearer)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	payload, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == CookieName && cookie.Value != "" {
+			c.mu.Lock()
+			c.token = cookie.Value
+			c.mu.Unlock()
+		}
+	}
+	return res, payload, nil
+}
+
+func decode(res *http.Response, payload []byte, out any, ok []int) error {
+	accepted := false
+	for _, code := range ok {
+		if res.StatusCode == code {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return newAPIError(res, payload)
+	}
+
+	if out == nil || len(bytes.TrimSpace(payload)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("client: decoding response: %w", err)
+	}
+	return nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Проверяет, что ошибка означает отсутствие ссылки
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.NotFound()
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IgorGreusunset/shortener/cmd/config"
+	model "github.com/IgorGreusunset/shortener/internal/app"
+	"github.com/IgorGreusunset/shortener/internal/handlers"
+	"github.com/IgorGreusunset/shortener/internal/middleware"
+	"github.com/IgorGreusunset/shortener/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// Сервер с настоящими handlers и хранилищем в памяти
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	db := storage.NewStorage(map[string]model.URL{})
+	router := chi.NewRouter()
+	router.Use(middleware.GzipMiddleware)
+	router.Use(middleware.WithAuth([]byte("test-key")))
+	router.Post(`/api/shorten`, handlers.APIPostHandler(db))
+	router.Post(`/api/shorten/batch`, handlers.BathcHandler(db))
+	router.Get(`/{id}`, handlers.GetByIDHandler(db))
+	router.Get(`/{id}/info`, handlers.InfoHandler(db))
+	router.Get(`/api/user/urls`, handlers.UserURLsHandler(db))
+	router.Delete(`/api/user/urls`, handlers.DeleteUserURLsHandler(db))
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	config.Base = srv.URL
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+
+	c, err := New(srv.URL, WithGzip())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	short, err := c.Shorten(ctx, ShortenRequest{URL: "https://mail.ru/"})
+	if err != nil {
+		t.Fatalf("Shorten: %v", err)
+	}
+	if c.Token() == "" {
+		t.Errorf("Expected client to keep user cookie issued by server")
+	}
+
+	batch, err := c.ShortenBatch(ctx, []BatchRequest{{ID: "1", URL: "https://ya.ru/"}, {ID: "2", URL: "https://go.dev/"}})
+	if err != nil {
+		t.Fatalf("ShortenBatch: %v", err)
+	}
+	if len(batch) != 2 {
+		t.Errorf("Batch response count didn't match expected: got %d want 2", len(batch))
+	}
+
+	res, err := c.Resolve(ctx, short.Result)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if res.Location != "https://mail.ru/" || res.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("Resolution didn't match expected: got %d %s", res.StatusCode, res.Location)
+	}
+
+	if _, err := c.Resolve(ctx, "yyokley"); !IsNotFound(err) {
+		t.Errorf("Expected not found error for unknown id, got %v", err)
+	}
+
+	links, err := c.ListUserURLs(ctx)
+	if err != nil {
+		t.Fatalf("ListUserURLs: %v", err)
+	}
+	if len(links) != 3 {
+		t.Errorf("User links count didn't match expected: got %d want 3", len(links))
+	}
+
+	//Другой пользователь не видит и не может удалить чужие ссылки
+	other, _ := New(srv.URL)
+	if links, err := other.ListUserURLs(ctx); err != nil || len(links) != 0 {
+		t.Errorf("Expected no links for other user, got %d, %v", len(links), err)
+	}
+	if err := other.DeleteURLs(ctx, short.Result); err != nil {
+		t.Fatalf("DeleteURLs: %v", err)
+	}
+
+	if err := c.DeleteURLs(ctx, short.Result, batch[0].ShortURL); err != nil {
+		t.Fatalf("DeleteURLs: %v", err)
+	}
+	links, _ = c.ListUserURLs(ctx)
+	if len(links) != 1 {
+		t.Errorf("User links count after delete didn't match expected: got %d want 1", len(links))
+	}
+
+	//Сохраненный токен позволяет продолжить работу новым клиентом
+	again, _ := New(srv.URL, WithToken(c.Token()))
+	if links, _ := again.ListUserURLs(ctx); len(links) != 1 {
+		t.Errorf("Expected links of the same user with saved token, got %d", len(links))
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) < 3 {
+			res.Header().Set("Retry-After", "0")
+			res.Header().Set("Content-Type", "application/json")
+			res.WriteHeader(http.StatusTooManyRequests)
+			res.Write([]byte(`{"error":"link quota exceeded","request_id":"req-1"}`))
+			return
+		}
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusCreated)
+		res.Write([]byte(`{"result":"http://localhost/abc"}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		maxAttempts int
+		wantErr     bool
+	}{
+		{name: "gives_up", maxAttempts: 2, wantErr: true},
+		{name: "succeeds", maxAttempts: 3, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			c, _ := New(srv.URL, WithRetry(RetryPolicy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+
+			_, err := c.Shorten(context.Background(), ShortenRequest{URL: "https://mail.ru/"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var apiErr *APIError
+			if tt.wantErr && (!errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests) {
+				t.Errorf("Expected 429 API error, got %v", err)
+			}
+			//Сообщение и идентификатор запроса берутся из JSON тела ошибки
+			if tt.wantErr && apiErr != nil && (apiErr.Message != "link quota exceeded" || apiErr.RequestID != "req-1") {
+				t.Errorf("API error didn't match expected: got %+v", apiErr)
+			}
+			if int(calls.Load()) != tt.maxAttempts {
+				t.Errorf("Attempts didn't match expected: got %d want %d", calls.Load(), tt.maxAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyNext(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	tests := []struct {
+		name   string
+		method string
+		status int
+		retry  bool
+	}{
+		{name: "post_429", method: http.MethodPost, status: http.StatusTooManyRequests, retry: true},
+		{name: "post_503", method: http.MethodPost, status: http.StatusServiceUnavailable, retry: true},
+		{name: "post_502", method: http.MethodPost, status: http.StatusBadGateway, retry: false},
+		{name: "post_504", method: http.MethodPost, status: http.StatusGatewayTimeout, retry: false},
+		{name: "get_502", method: http.MethodGet, status: http.StatusBadGateway, retry: true},
+		{name: "delete_504", method: http.MethodDelete, status: http.StatusGatewayTimeout, retry: true},
+		{name: "get_500", method: http.MethodGet, status: http.StatusInternalServerError, retry: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, _ := p.next(1, tt.method, &http.Response{StatusCode: tt.status, Header: http.Header{}}, nil)
+			if retry != tt.retry {
+				t.Errorf("Retry decision didn't match expected: got %v want %v", retry, tt.retry)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Ошибка ответа сервера с неожиданным кодом
+type APIError struct {
+	StatusCode int
+	Message    string
+	//Идентификатор запроса из заголовка X-Request-ID для поиска в логах сервера
+	RequestID string
+}
+
+// Тело ошибки в JSON, которым отвечают, например, при превышении квоты
+type errorBody struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id"`
+}
+
+func newAPIError(res *http.Response, payload []byte) *APIError {
+	e := &APIError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(payload)), RequestID: res.Header.Get("X-Request-ID")}
+
+	var body errorBody
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "application/json") && json.Unmarshal(payload, &body) == nil && body.Error != "" {
+		e.Message = body.Error
+		if e.RequestID == "" {
+			e.RequestID = body.RequestID
+		}
+	}
+	if len(e.Message) > 512 {
+		e.Message = e.Message[:512]
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	text := fmt.Sprintf("shortener: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		text += " (request id " + e.RequestID + ")"
+	}
+	return text
+}
+
+// Сервер отвечает 400 на переход по неизвестному ID и 404 в остальных API
+func (e *APIError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusBadRequest && e.Message == ""
+}
+
+// Полная ссылка уже сокращена, ShortURL - существующая короткая ссылка
+type ExistsError struct {
+	ShortURL string
+}
+
+func (e *ExistsError) Error() string {
+	return "shortener: url already shortened as " + e.ShortURL
+}
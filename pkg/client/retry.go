@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Политика повторов. Ответы 429 и 503 означают, что сервер отклонил запрос, не обрабатывая его, и повторяются
+// для всех методов. Ответы 502 и 504 и сетевые ошибки приходят и тогда, когда сервер уже выполнил запрос,
+// поэтому повторяются только для идемпотентных методов, чтобы не создать ссылку дважды
+type RetryPolicy struct {
+	//Максимальное число попыток, 1 отключает повторы
+	MaxAttempts int
+	//Задержка перед первым повтором, каждая следующая удваивается
+	BaseDelay time.Duration
+	//Верхняя граница задержки, в том числе из Retry-After
+	MaxDelay time.Duration
+}
+
+// Политика по умолчанию: до трех попыток с задержкой от 200 мс
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// Решает, повторять ли попытку attempt, и возвращает задержку перед повтором
+func (p RetryPolicy) next(attempt int, method string, res *http.Response, err error) (bool, time.Duration) {
+	if attempt >= p.MaxAttempts {
+		return false, 0
+	}
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || !idempotent(method) || !temporary(err) {
+			return false, 0
+		}
+		return true, p.backoff(attempt)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		if !idempotent(method) {
+			return false, 0
+		}
+	default:
+		return false, 0
+	}
+
+	//Сервер сообщает, когда корзина ограничения частоты пополнится
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		return true, min(time.Duration(secs)*time.Second, p.MaxDelay)
+	}
+	return true, p.backoff(attempt)
+}
+
+// Экспоненциальная задержка со случайным разбросом, чтобы клиенты не повторяли запросы одновременно
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func temporary(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}